@@ -0,0 +1,143 @@
+// Псевдонимы сущностей Musicbrainz (https://musicbrainz.org/doc/Aliases) и
+// локализация наименований.
+
+package musicbrainz
+
+import (
+	"strings"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+// OriginalNamesKey - ключ дополнительных сведений релиза с JSON-словарем исходных
+// наименований Musicbrainz по локализованным наименованиям артистов, лейблов,
+// произведений и группы релизов.
+const OriginalNamesKey = "original_names"
+
+// Тип псевдонима, предназначенного только для поиска.
+const searchHintAlias = "Search hint"
+
+type alias struct {
+	Name     string `json:"name"`
+	SortName string `json:"sort-name"`
+	Locale   string `json:"locale"`
+	Primary  bool   `json:"primary"`
+	Type     string `json:"type"`
+}
+
+type aliases []alias
+
+// Name возвращает наименование для локали (например, "ru" или "en_US"): основной
+// псевдоним локали, а при его отсутствии - любой другой ее псевдоним. Псевдонимы
+// с совпадением только языка локали используются, если нет точного совпадения.
+func (as aliases) Name(locale string) string {
+	if locale == "" {
+		return ""
+	}
+	var ret string
+	best := 0
+	lang := language(locale)
+	for _, a := range as {
+		if a.Type == searchHintAlias || a.Locale == "" {
+			continue
+		}
+		var score int
+		switch {
+		case strings.EqualFold(a.Locale, locale):
+			score = 2
+		case lang != "" && strings.EqualFold(language(a.Locale), lang):
+			score = 1
+		default:
+			continue
+		}
+		if a.Primary {
+			score += 2
+		}
+		if score > best {
+			ret, best = a.Name, score
+		}
+	}
+	return ret
+}
+
+// Язык локали ("ru" для "ru_RU" или "ru-RU"; "" для локали из одних разделителей).
+func language(locale string) string {
+	fields := strings.FieldsFunc(locale, func(r rune) bool { return r == '_' || r == '-' })
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// localizer заменяет наименования сущностей псевдонимами для локали и сохраняет
+// исходные наименования.
+type localizer struct {
+	locale    string
+	originals map[string]string
+}
+
+func (l *localizer) name(name *string, as aliases) {
+	localized := as.Name(l.locale)
+	if localized == "" || localized == *name {
+		return
+	}
+	l.originals[localized] = *name
+	*name = localized
+}
+
+// Имя в указании артиста, совпадающее с именем артиста в Musicbrainz, также
+// локализуется: CreditedAs и полное указание исполнителей строятся по тем же
+// именам, что и ключи акторов.
+func (l *localizer) artistCredits(acs artistCredits) {
+	for i := range acs {
+		original := acs[i].Artist.Name
+		l.name(&acs[i].Artist.Name, acs[i].Artist.Aliases)
+		if acs[i].Name == original {
+			acs[i].Name = acs[i].Artist.Name
+		}
+	}
+}
+
+func (l *localizer) relations(rels []relation) {
+	for i := range rels {
+		l.name(&rels[i].Artist.Name, rels[i].Artist.Aliases)
+		if rels[i].Work != nil {
+			l.name(&rels[i].Work.Title, rels[i].Work.Aliases)
+		}
+	}
+}
+
+// Замена наименований релиза псевдонимами для локали. Исходные наименования
+// сохраняются в дополнительных сведениях релиза по ключу OriginalNamesKey.
+func (ri *releaseInfo) localize(r *md.Release, locale string) {
+	if locale == "" {
+		return
+	}
+	l := &localizer{locale: locale, originals: map[string]string{}}
+	l.artistCredits(ri.ArtistCredit)
+	l.relations(ri.Relations)
+	l.name(&ri.ReleaseGroup.Title, ri.ReleaseGroup.Aliases)
+	l.relations(ri.ReleaseGroup.Relations)
+	for i := range ri.LabelInfo {
+		l.name(&ri.LabelInfo[i].Label.Name, ri.LabelInfo[i].Label.Aliases)
+	}
+	for i := range ri.Media {
+		for j := range ri.Media[i].Tracks {
+			tr := &ri.Media[i].Tracks[j]
+			l.artistCredits(tr.ArtistCredits)
+			l.artistCredits(tr.Recording.ArtistCredits)
+			l.relations(tr.Recording.Relations)
+		}
+	}
+	if len(l.originals) > 0 {
+		setExtraJSON(r.Unprocessed, OriginalNamesKey, l.originals)
+	}
+}
+
+// OriginalNames возвращает исходные наименования Musicbrainz по локализованным
+// наименованиям.
+func OriginalNames(r *md.Release) (map[string]string, error) {
+	var ret map[string]string
+	err := extraJSON(r.Unprocessed, OriginalNamesKey, &ret)
+	return ret, err
+}
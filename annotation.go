@@ -0,0 +1,119 @@
+// Преобразование аннотаций Musicbrainz (https://musicbrainz.org/doc/Annotation)
+// из вики-разметки в простой текст или Markdown.
+
+package musicbrainz
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NotesFormat определяет форму представления аннотаций в примечаниях (Notes).
+type NotesFormat string
+
+// Формы представления аннотаций.
+const (
+	NotesText     NotesFormat = "text"     // простой текст
+	NotesMarkdown NotesFormat = "markdown" // Markdown
+	NotesRaw      NotesFormat = "raw"      // исходная вики-разметка Musicbrainz
+)
+
+// Адрес страниц сущностей Musicbrainz для ссылок вида [artist:<mbid>|name].
+const entityURL = "https://musicbrainz.org/"
+
+var (
+	annotationLink       = regexp.MustCompile(`\[([^\[\]|]+)(?:\|([^\[\]]*))?\]`)
+	annotationBoldItalic = regexp.MustCompile(`'''''(.+?)'''''`)
+	annotationBold       = regexp.MustCompile(`'''(.+?)'''`)
+	annotationEmpty      = regexp.MustCompile(`'{4,}`)
+	annotationItalic     = regexp.MustCompile(`''(.+?)''`)
+	annotationHeading    = regexp.MustCompile(`^(=+)\s*(.*?)\s*=+$`)
+	annotationList       = regexp.MustCompile(`^([*#]+)\s*(.*)$`)
+	annotationRule       = regexp.MustCompile(`^-{4,}$`)
+	entityLink           = regexp.MustCompile(`^([a-z-]+):([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+)
+
+// RenderAnnotation преобразует аннотацию в указанную форму.
+func RenderAnnotation(s string, f NotesFormat) string {
+	if f == NotesRaw || s == "" {
+		return s
+	}
+	markdown := f == NotesMarkdown
+	var lines []string
+	counters := map[int]int{}
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if !annotationList.MatchString(line) {
+			counters = map[int]int{}
+		}
+		switch {
+		case annotationRule.MatchString(line):
+			if markdown {
+				line = "---"
+			} else {
+				line = ""
+			}
+		case annotationHeading.MatchString(line):
+			m := annotationHeading.FindStringSubmatch(line)
+			line = renderInline(m[2], markdown)
+			if markdown {
+				line = strings.Repeat("#", len(m[1])) + " " + line
+			}
+		case annotationList.MatchString(line):
+			m := annotationList.FindStringSubmatch(line)
+			level := len(m[1])
+			indent := strings.Repeat("  ", level-1)
+			marker := "-"
+			if strings.HasSuffix(m[1], "#") {
+				counters[level]++
+				for l := range counters {
+					if l > level {
+						delete(counters, l)
+					}
+				}
+				marker = strconv.Itoa(counters[level]) + "."
+			}
+			line = indent + marker + " " + renderInline(m[2], markdown)
+		default:
+			line = renderInline(line, markdown)
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// Преобразование ссылок и выделения текста в строке.
+func renderInline(s string, markdown bool) string {
+	s = annotationLink.ReplaceAllStringFunc(s, func(link string) string {
+		m := annotationLink.FindStringSubmatch(link)
+		target, text := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if em := entityLink.FindStringSubmatch(target); em != nil {
+			target = entityURL + em[1] + "/" + em[2]
+			if !markdown && text != "" {
+				return text
+			}
+		} else if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return link
+		}
+		switch {
+		case markdown && text != "":
+			return "[" + text + "](" + target + ")"
+		case markdown:
+			return "<" + target + ">"
+		case text != "" && text != target:
+			return text + " (" + target + ")"
+		}
+		return target
+	})
+	boldItalic, bold, italic := "$1", "$1", "$1"
+	if markdown {
+		boldItalic, bold, italic = "***$1***", "**$1**", "*$1*"
+	}
+	// полужирный курсив обрабатывается до полужирного и курсива, иначе от
+	// разметки остаются лишние кавычки; пустое выделение ('''', '''''') удаляется
+	s = annotationBoldItalic.ReplaceAllString(s, boldItalic)
+	s = annotationEmpty.ReplaceAllString(s, "")
+	s = annotationBold.ReplaceAllString(s, bold)
+	return annotationItalic.ReplaceAllString(s, italic)
+}
@@ -0,0 +1,192 @@
+// Просмотр сущностей, связанных с артистом, лейблом или коллекцией
+// (https://musicbrainz.org/doc/MusicBrainz_API#Browse).
+
+package musicbrainz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Количество результатов на странице ответа (максимум Musicbrainz).
+const browseLimit = 100
+
+// Просматриваемые сущности.
+const (
+	BrowseReleases      = "release"
+	BrowseReleaseGroups = "release-group"
+	BrowseRecordings    = "recording"
+)
+
+// Включаемые в ответ сведения для просматриваемых сущностей.
+var browseInc = map[string]string{
+	BrowseReleases:      "artist-credits+labels+release-groups",
+	BrowseReleaseGroups: "artist-credits+genres+tags+ratings",
+	BrowseRecordings:    "artist-credits+isrcs+genres+tags+ratings",
+}
+
+// BrowseRequest описывает просмотр релизов, групп релизов или записей. Указывается
+// ровно один из идентификаторов Artist, Label или Collection; лейбл и коллекция
+// допустимы только для релизов.
+type BrowseRequest struct {
+	Entity     string `json:"entity"`               // "release", "release-group" или "recording"
+	Artist     string `json:"artist,omitempty"`     // MBID артиста
+	Label      string `json:"label,omitempty"`      // MBID лейбла
+	Collection string `json:"collection,omitempty"` // MBID коллекции
+	// Типы групп релизов ("album", "ep", "live"...) для релизов и групп релизов.
+	Types []string `json:"types,omitempty"`
+	// Статусы релизов ("official", "promotion", "bootleg", "pseudo-release").
+	Statuses []string `json:"statuses,omitempty"`
+	// Максимальное количество результатов (0 - без ограничения).
+	Limit int `json:"limit,omitempty"`
+}
+
+// Ответ Musicbrainz на просмотр сущностей (одна страница или все загруженные
+// страницы).
+type browseResult struct {
+	ReleaseCount      int                 `json:"release-count"`
+	ReleaseGroupCount int                 `json:"release-group-count"`
+	RecordingCount    int                 `json:"recording-count"`
+	Releases          []releaseSearchItem `json:"releases"`
+	ReleaseGroups     []releaseGroup      `json:"release-groups"`
+	Recordings        []recording         `json:"recordings"`
+}
+
+// Общее количество результатов.
+func (br *browseResult) count() int {
+	return br.ReleaseCount + br.ReleaseGroupCount + br.RecordingCount
+}
+
+// Количество загруженных результатов.
+func (br *browseResult) len() int {
+	return len(br.Releases) + len(br.ReleaseGroups) + len(br.Recordings)
+}
+
+func (br *browseResult) append(page *browseResult) {
+	br.ReleaseCount = page.ReleaseCount
+	br.ReleaseGroupCount = page.ReleaseGroupCount
+	br.RecordingCount = page.RecordingCount
+	br.Releases = append(br.Releases, page.Releases...)
+	br.ReleaseGroups = append(br.ReleaseGroups, page.ReleaseGroups...)
+	br.Recordings = append(br.Recordings, page.Recordings...)
+}
+
+func (br *browseResult) truncate(n int) {
+	if n <= 0 {
+		return
+	}
+	if len(br.Releases) > n {
+		br.Releases = br.Releases[:n]
+	}
+	if len(br.ReleaseGroups) > n {
+		br.ReleaseGroups = br.ReleaseGroups[:n]
+	}
+	if len(br.Recordings) > n {
+		br.Recordings = br.Recordings[:n]
+	}
+}
+
+// Проверка корректности запроса.
+func (req *BrowseRequest) validate() error {
+	if _, ok := browseInc[req.Entity]; !ok {
+		return fmt.Errorf("unsupported browse entity %q", req.Entity)
+	}
+	var links int
+	for _, id := range []string{req.Artist, req.Label, req.Collection} {
+		if id != "" {
+			links++
+		}
+	}
+	if links != 1 {
+		return errors.New("exactly one of artist, label or collection must be specified")
+	}
+	if req.Entity != BrowseReleases && req.Artist == "" {
+		return fmt.Errorf("%s can only be browsed by artist", req.Entity)
+	}
+	if req.Entity == BrowseRecordings && len(req.Types) > 0 {
+		return errors.New("types filter is not applicable to recordings")
+	}
+	if req.Entity != BrowseReleases && len(req.Statuses) > 0 {
+		return errors.New("statuses filter is applicable to releases only")
+	}
+	return nil
+}
+
+// Адрес первой страницы просмотра без параметров limit и offset.
+func browseURL(baseURL string, req *BrowseRequest) string {
+	link, id := "artist", req.Artist
+	if req.Label != "" {
+		link, id = "label", req.Label
+	} else if req.Collection != "" {
+		link, id = "collection", req.Collection
+	}
+	ret := baseURL + req.Entity + "?" + link + "=" + url.QueryEscape(id)
+	if len(req.Types) > 0 {
+		ret += "&type=" + url.QueryEscape(strings.ToLower(strings.Join(req.Types, "|")))
+	}
+	if len(req.Statuses) > 0 {
+		ret += "&status=" + url.QueryEscape(strings.ToLower(strings.Join(req.Statuses, "|")))
+	}
+	return ret + "&inc=" + browseInc[req.Entity] + "&fmt=json"
+}
+
+// Загрузка всех страниц результатов просмотра (не более max результатов при max > 0).
+func (m *Musicbrainz) browseAll(u string, max int) (*browseResult, error) {
+	ret := &browseResult{}
+	for {
+		var page browseResult
+		if err := m.decodeJSON(
+			fmt.Sprintf("%s&limit=%d&offset=%d", u, browseLimit, ret.len()), &page); err != nil {
+			return nil, err
+		}
+		ret.append(&page)
+		if page.len() == 0 || ret.len() >= ret.count() || (max > 0 && ret.len() >= max) {
+			break
+		}
+	}
+	ret.truncate(max)
+	return ret, nil
+}
+
+// Просмотр сущностей и преобразование результатов в релизы или треки.
+func (m *Musicbrainz) browseEntities(req *BrowseRequest, mp *mapping) (*AudioOnlineResponse, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	result, err := m.browseAll(browseURL(m.baseURL, req), req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &AudioOnlineResponse{}
+	for _, si := range result.Releases {
+		r := si.Release()
+		r.Country = si.Country
+		setReleaseDate(r.ReleaseStub, si.Date)
+		setReleaseDate(r.Original, si.ReleaseGroup.FirstReleaseDate)
+		resp.Releases = append(resp.Releases, r)
+	}
+	for _, rgi := range result.ReleaseGroups {
+		r := rgi.Release(mp)
+		resp.Releases = append(resp.Releases, r)
+	}
+	for _, rec := range result.Recordings {
+		tr := rec.Track(mp)
+		tr.Clean()
+		resp.Tracks = append(resp.Tracks, tr)
+	}
+	return resp, nil
+}
+
+func (m *Musicbrainz) browse(request *AudioOnlineRequest) ([]byte, error) {
+	if request.Browse == nil {
+		return nil, errors.New("browse parameters are not specified")
+	}
+	resp, err := m.browseEntities(request.Browse, m.mapping(request))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
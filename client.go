@@ -14,14 +14,34 @@ import (
 type AudioOnlineRequest struct {
 	Cmd     string      `json:"cmd"`
 	Release *md.Release `json:"release"`
+	// Ограничения поиска по типам групп релизов.
+	Types *ReleaseTypeFilter `json:"types,omitempty"`
+	// Локаль наименований артистов, лейблов и произведений ("ru", "en"...).
+	Locale string `json:"locale,omitempty"`
+	// Письменность списка треков (ISO 15924: "Latn", "Jpan"...). Если релиз
+	// использует другую письменность, возвращается его связанная версия.
+	Script string `json:"script,omitempty"`
+	// Форма представления аннотаций в примечаниях ("text", "markdown", "raw").
+	NotesFormat NotesFormat `json:"notes_format,omitempty"`
+	// Параметры просмотра релизов, групп релизов или записей (команда "browse").
+	Browse *BrowseRequest `json:"browse,omitempty"`
+	// Параметры запроса дискографии артиста (команда "discography").
+	Discography *DiscographyRequest `json:"discography,omitempty"`
 	// Actor
 	// *md.Publishing
 }
 
 // AudioOnlineResponse описывает структуру ответа микросервиса.
 type AudioOnlineResponse struct {
-	SuggestionSet *md.SuggestionSet  `json:"suggestion_set,omitempty"`
-	Error         *srv.ErrorResponse `json:"error,omitempty"`
+	SuggestionSet *md.SuggestionSet `json:"suggestion_set,omitempty"`
+	// Результаты просмотра релизов или групп релизов. Группа релизов представлена
+	// релизом, у которого заполнены только сведения оригинала и исполнители.
+	Releases []*md.Release `json:"releases,omitempty"`
+	// Результаты просмотра записей.
+	Tracks []*md.Track `json:"tracks,omitempty"`
+	// Дискография артиста.
+	Discography *Discography       `json:"discography,omitempty"`
+	Error       *srv.ErrorResponse `json:"error,omitempty"`
 }
 
 // type AudioOnlineDBClient struct {
@@ -59,3 +79,39 @@ func ParseReleaseAnswer(data []byte) (*AudioOnlineResponse, error) {
 	}
 	return &resp, nil
 }
+
+// CreateBrowseRequest формирует данные запроса просмотра релизов, групп релизов
+// или записей.
+func CreateBrowseRequest(br *BrowseRequest) (string, []byte, error) {
+	correlationID, _ := uuid.NewV4()
+	req := AudioOnlineRequest{
+		Cmd:    "browse",
+		Browse: br}
+	data, err := json.Marshal(&req)
+	if err != nil {
+		return "", nil, err
+	}
+	return correlationID.String(), data, nil
+}
+
+// ParseBrowseAnswer разбирает ответ с результатами просмотра.
+func ParseBrowseAnswer(data []byte) (*AudioOnlineResponse, error) {
+	var resp AudioOnlineResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateDiscographyRequest формирует данные запроса дискографии артиста.
+func CreateDiscographyRequest(artist string, official bool) (string, []byte, error) {
+	correlationID, _ := uuid.NewV4()
+	req := AudioOnlineRequest{
+		Cmd:         "discography",
+		Discography: &DiscographyRequest{Artist: artist, Official: official}}
+	data, err := json.Marshal(&req)
+	if err != nil {
+		return "", nil, err
+	}
+	return correlationID.String(), data, nil
+}
@@ -0,0 +1,97 @@
+package musicbrainz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+// DatePrecision определяет точность частичной даты Musicbrainz.
+type DatePrecision int8
+
+// Варианты точности даты.
+const (
+	PrecisionNone DatePrecision = iota
+	PrecisionYear
+	PrecisionMonth
+	PrecisionDay
+)
+
+// ReleaseDateKey - ключ даты выпуска (релиза или оригинального релиза) в
+// дополнительных сведениях md.ReleaseStub.Unprocessed в формате YYYY[-MM[-DD]].
+const ReleaseDateKey = "release_date"
+
+// Date описывает частичную дату Musicbrainz (YYYY, YYYY-MM или YYYY-MM-DD).
+type Date struct {
+	Year      int
+	Month     int
+	Day       int
+	Precision DatePrecision
+}
+
+// ParseDate разбирает частичную дату Musicbrainz. Точность определяется
+// последним корректно разобранным компонентом даты.
+func ParseDate(s string) Date {
+	var d Date
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 3)
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v <= 0 {
+			break
+		}
+		switch i {
+		case 0:
+			d.Year = v
+		case 1:
+			if v > 12 {
+				return d
+			}
+			d.Month = v
+		case 2:
+			if v > 31 {
+				return d
+			}
+			d.Day = v
+		}
+		d.Precision = DatePrecision(i + 1)
+	}
+	return d
+}
+
+// IsZero возвращает true для пустой даты.
+func (d Date) IsZero() bool {
+	return d.Precision == PrecisionNone
+}
+
+// String возвращает дату в формате Musicbrainz с учетом ее точности.
+func (d Date) String() string {
+	switch d.Precision {
+	case PrecisionYear:
+		return fmt.Sprintf("%04d", d.Year)
+	case PrecisionMonth:
+		return fmt.Sprintf("%04d-%02d", d.Year, d.Month)
+	case PrecisionDay:
+		return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+	}
+	return ""
+}
+
+// ReleaseDate возвращает дату выпуска релиза или оригинального релиза.
+func ReleaseDate(stub *md.ReleaseStub) Date {
+	if stub == nil {
+		return Date{}
+	}
+	return ParseDate(stub.Unprocessed[ReleaseDateKey])
+}
+
+// Сохранение даты выпуска с заполнением года.
+func setReleaseDate(stub *md.ReleaseStub, s string) {
+	d := ParseDate(s)
+	if d.IsZero() {
+		return
+	}
+	stub.Year = d.Year
+	stub.Unprocessed[ReleaseDateKey] = d.String()
+}
@@ -0,0 +1,183 @@
+// Дискография артиста: группы релизов, сгруппированные по типам.
+
+package musicbrainz
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Порядок разделов дискографии по основному типу группы релизов. Прочие типы
+// следуют за перечисленными в алфавитном порядке, группы без типа - в конце.
+var primaryTypeOrder = []string{"Album", "EP", "Single", "Broadcast", "Other"}
+
+// Статус официального релиза Musicbrainz.
+const officialStatus = "Official"
+
+// DiscographyRequest описывает запрос дискографии артиста.
+type DiscographyRequest struct {
+	Artist string `json:"artist"` // MBID артиста
+	// Только группы релизов, имеющие официальные релизы.
+	Official bool `json:"official,omitempty"`
+}
+
+// Discography описывает дискографию артиста.
+type Discography struct {
+	Artist   string               `json:"artist"`
+	Sections []DiscographySection `json:"sections"`
+}
+
+// DiscographySection описывает группы релизов с одинаковыми основным и
+// дополнительными типами.
+type DiscographySection struct {
+	PrimaryType    string             `json:"primary_type,omitempty"`
+	SecondaryTypes []string           `json:"secondary_types,omitempty"`
+	ReleaseGroups  []DiscographyEntry `json:"release_groups"`
+}
+
+// DiscographyEntry описывает группу релизов дискографии и ее представительный
+// релиз (самый ранний, с предпочтением официальных).
+type DiscographyEntry struct {
+	ID               string `json:"id"`
+	Title            string `json:"title"`
+	FirstReleaseDate string `json:"first_release_date,omitempty"`
+	ReleaseID        string `json:"release_id,omitempty"`
+	Releases         int    `json:"releases"`
+}
+
+// Ключ раздела дискографии.
+func (ds *DiscographySection) key() string {
+	return ds.PrimaryType + "\x00" + strings.Join(ds.SecondaryTypes, "\x00")
+}
+
+// Порядковый номер раздела по основному типу.
+func (ds *DiscographySection) rank() int {
+	if ds.PrimaryType == "" {
+		return len(primaryTypeOrder) + 1
+	}
+	for i, pt := range primaryTypeOrder {
+		if strings.EqualFold(pt, ds.PrimaryType) {
+			return i
+		}
+	}
+	return len(primaryTypeOrder)
+}
+
+// Сравнение дат выпуска в формате Musicbrainz ("2006", "2006-05-17"...);
+// неизвестная дата следует за известными.
+func earlier(a, b string) bool {
+	if a == "" || b == "" {
+		return b == "" && a != ""
+	}
+	return a < b
+}
+
+// Представительный релиз заменяется, если очередной релиз официальный, а текущий
+// нет, либо при равенстве статусов очередной выпущен раньше.
+func betterRelease(si, current *releaseSearchItem) bool {
+	if current == nil {
+		return true
+	}
+	siOfficial := strings.EqualFold(si.Status, officialStatus)
+	curOfficial := strings.EqualFold(current.Status, officialStatus)
+	if siOfficial != curOfficial {
+		return siOfficial
+	}
+	return earlier(si.Date, current.Date)
+}
+
+// Сборка дискографии из групп релизов и релизов артиста. При official группы
+// релизов без официальных релизов пропускаются.
+func newDiscography(artist string, groups []releaseGroup, releases []releaseSearchItem, official bool) *Discography {
+	best := map[string]*releaseSearchItem{}
+	counts := map[string]int{}
+	for i := range releases {
+		si := &releases[i]
+		if official && !strings.EqualFold(si.Status, officialStatus) {
+			continue
+		}
+		rgID := si.ReleaseGroup.ID
+		counts[rgID]++
+		if betterRelease(si, best[rgID]) {
+			best[rgID] = si
+		}
+	}
+	ret := &Discography{Artist: artist}
+	sections := map[string]*DiscographySection{}
+	var order []*DiscographySection
+	for _, rgi := range groups {
+		if official && counts[rgi.ID] == 0 {
+			continue
+		}
+		ds := &DiscographySection{PrimaryType: rgi.PrimaryType, SecondaryTypes: rgi.SecondaryTypes}
+		if s, ok := sections[ds.key()]; ok {
+			ds = s
+		} else {
+			sections[ds.key()] = ds
+			order = append(order, ds)
+		}
+		entry := DiscographyEntry{
+			ID:               rgi.ID,
+			Title:            rgi.Title,
+			FirstReleaseDate: rgi.FirstReleaseDate,
+			Releases:         counts[rgi.ID]}
+		if si := best[rgi.ID]; si != nil {
+			entry.ReleaseID = si.ID
+		}
+		ds.ReleaseGroups = append(ds.ReleaseGroups, entry)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if ri, rj := order[i].rank(), order[j].rank(); ri != rj {
+			return ri < rj
+		}
+		if order[i].PrimaryType != order[j].PrimaryType {
+			return order[i].PrimaryType < order[j].PrimaryType
+		}
+		return strings.Join(order[i].SecondaryTypes, "+") < strings.Join(order[j].SecondaryTypes, "+")
+	})
+	for _, ds := range order {
+		entries := ds.ReleaseGroups
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].FirstReleaseDate != entries[j].FirstReleaseDate {
+				return earlier(entries[i].FirstReleaseDate, entries[j].FirstReleaseDate)
+			}
+			return entries[i].Title < entries[j].Title
+		})
+		ret.Sections = append(ret.Sections, *ds)
+	}
+	return ret
+}
+
+// Загрузка групп релизов и релизов артиста и сборка дискографии.
+func (m *Musicbrainz) artistDiscography(req *DiscographyRequest) (*Discography, error) {
+	if req.Artist == "" {
+		return nil, errors.New("artist must be specified")
+	}
+	groups, err := m.browseAll(browseURL(m.baseURL,
+		&BrowseRequest{Entity: BrowseReleaseGroups, Artist: req.Artist}), 0)
+	if err != nil {
+		return nil, err
+	}
+	br := &BrowseRequest{Entity: BrowseReleases, Artist: req.Artist}
+	if req.Official {
+		br.Statuses = []string{officialStatus}
+	}
+	releases, err := m.browseAll(browseURL(m.baseURL, br), 0)
+	if err != nil {
+		return nil, err
+	}
+	return newDiscography(req.Artist, groups.ReleaseGroups, releases.Releases, req.Official), nil
+}
+
+func (m *Musicbrainz) discography(request *AudioOnlineRequest) ([]byte, error) {
+	if request.Discography == nil {
+		return nil, errors.New("discography parameters are not specified")
+	}
+	d, err := m.artistDiscography(request.Discography)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(AudioOnlineResponse{Discography: d})
+}
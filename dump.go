@@ -0,0 +1,388 @@
+// Локальный источник данных на основе JSON-дампов Musicbrainz
+// (https://musicbrainz.org/doc/MusicBrainz_Database/Download).
+//
+// Каталог дампа должен содержать распакованные файлы сущностей (release, artist,
+// label, recording), в которых каждая строка описывает одну сущность в формате WS/2.
+// При первом открытии строится индекс, который сохраняется в каталоге дампа и
+// используется при последующих запусках, пока файлы дампа не изменятся.
+
+package musicbrainz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Сущности, индексируемые в дампе.
+var dumpEntities = []string{"release", "artist", "label", "recording"}
+
+// Наименование файла индекса в каталоге дампа.
+const dumpIndexFile = "musicbrainz.index"
+
+// Максимальное количество результатов поиска (как у WS/2 по умолчанию).
+const dumpSearchLimit = 25
+
+// ErrNotInDump возвращается, если запрошенный ресурс отсутствует в дампе.
+var ErrNotInDump = errors.New("resource not found in dump")
+
+var luceneTerm = regexp.MustCompile(`(\w+):"((?:[^"\\]|\\.)*)"`)
+
+// Положение записи сущности в файле дампа.
+type dumpRecord struct {
+	Offset int64
+	Size   int
+}
+
+// Данные релиза, используемые при поиске.
+type dumpSearchEntry struct {
+	ID        string
+	Title     string
+	Artists   []string
+	ArtistIDs []string
+	Labels    []string
+	Catnos    []string
+	Barcode   string
+}
+
+// Размер и время изменения файла дампа на момент построения индекса.
+type dumpFile struct {
+	Size    int64
+	ModTime int64
+}
+
+type dumpIndex struct {
+	Files    map[string]dumpFile
+	Records  map[string]map[string]dumpRecord
+	Releases []dumpSearchEntry
+}
+
+// Dump описывает проиндексированный локальный дамп Musicbrainz.
+type Dump struct {
+	dir   string
+	files map[string]*os.File
+	index dumpIndex
+}
+
+// OpenDump открывает дамп в указанном каталоге, при необходимости строя индекс.
+func OpenDump(dir string) (*Dump, error) {
+	d := &Dump{dir: dir, files: map[string]*os.File{}}
+	for _, entity := range dumpEntities {
+		f, err := os.Open(d.entityPath(entity))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			d.Close()
+			return nil, err
+		}
+		d.files[entity] = f
+	}
+	if len(d.files) == 0 {
+		return nil, fmt.Errorf("no dump files in %s", dir)
+	}
+	if err := d.loadIndex(); err != nil {
+		if err = d.buildIndex(); err != nil {
+			d.Close()
+			return nil, err
+		}
+		if err = d.saveIndex(); err != nil {
+			d.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Close освобождает файлы дампа.
+func (d *Dump) Close() error {
+	var ret error
+	for _, f := range d.files {
+		if err := f.Close(); err != nil && ret == nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// Fetch возвращает данные сущности, соответствующей WS/2 URL, в виде http-ответа.
+// Поддерживаются запросы сущности по ID и поиск релизов. Отсутствующие в дампе
+// ресурсы возвращаются с кодом 404.
+func (d *Dump) Fetch(rawurl string, headers map[string]string) (*http.Response, error) {
+	data, err := d.resource(rawurl)
+	if errors.Is(err, ErrNotInDump) {
+		return dumpResponse(http.StatusNotFound, []byte(err.Error())), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dumpResponse(http.StatusOK, data), nil
+}
+
+func (d *Dump) resource(rawurl string) ([]byte, error) {
+	entity, id, query, err := parseWSURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		return d.record(entity, id)
+	}
+	if entity == "release" && query != "" {
+		res, err := d.searchReleases(query)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(res)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotInDump, rawurl)
+}
+
+func dumpResponse(statusCode int, data []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}
+}
+
+func (d *Dump) entityPath(entity string) string {
+	if _, err := os.Stat(filepath.Join(d.dir, "mbdump")); err == nil {
+		return filepath.Join(d.dir, "mbdump", entity)
+	}
+	return filepath.Join(d.dir, entity)
+}
+
+func (d *Dump) record(entity, id string) ([]byte, error) {
+	f, ok := d.files[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotInDump, entity, id)
+	}
+	rec, ok := d.index.Records[entity][id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotInDump, entity, id)
+	}
+	data := make([]byte, rec.Size)
+	if _, err := f.ReadAt(data, rec.Offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (d *Dump) searchReleases(query string) (*releaseSearchResult, error) {
+	terms := map[string]string{}
+	for _, m := range luceneTerm.FindAllStringSubmatch(query, -1) {
+		terms[m[1]] = strings.ToLower(strings.ReplaceAll(m[2], `\"`, `"`))
+	}
+	res := &releaseSearchResult{}
+	for _, entry := range d.index.Releases {
+		if !entry.match(terms) {
+			continue
+		}
+		res.Count++
+		if len(res.Releases) == dumpSearchLimit {
+			continue
+		}
+		data, err := d.record("release", entry.ID)
+		if err != nil {
+			return nil, err
+		}
+		var item releaseSearchItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		item.Score = 100
+		res.Releases = append(res.Releases, item)
+	}
+	return res, nil
+}
+
+func (entry *dumpSearchEntry) match(terms map[string]string) bool {
+	for k, v := range terms {
+		var ok bool
+		switch k {
+		case "release":
+			ok = strings.Contains(strings.ToLower(entry.Title), v)
+		case "artist":
+			ok = containsFold(entry.Artists, v)
+		case "arid":
+			ok = containsFold(entry.ArtistIDs, v)
+		case "label":
+			ok = containsFold(entry.Labels, v)
+		case "catno":
+			ok = containsFold(entry.Catnos, v)
+		case "barcode":
+			ok = entry.Barcode == v
+		default:
+			ok = true
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Dump) buildIndex() error {
+	files, err := d.fileStats()
+	if err != nil {
+		return err
+	}
+	d.index = dumpIndex{Files: files, Records: map[string]map[string]dumpRecord{}}
+	for entity, f := range d.files {
+		records := map[string]dumpRecord{}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		rd := bufio.NewReader(f)
+		var offset int64
+		for {
+			line, err := rd.ReadBytes('\n')
+			if len(line) > 0 {
+				if err := d.indexRecord(entity, line, offset, records); err != nil {
+					return fmt.Errorf("%s at offset %d: %w", entity, offset, err)
+				}
+				offset += int64(len(line))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		d.index.Records[entity] = records
+	}
+	return nil
+}
+
+func (d *Dump) indexRecord(entity string, line []byte, offset int64, records map[string]dumpRecord) error {
+	if len(strings.TrimSpace(string(line))) == 0 {
+		return nil
+	}
+	if entity != "release" {
+		var item struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return err
+		}
+		records[item.ID] = dumpRecord{Offset: offset, Size: len(line)}
+		return nil
+	}
+	var item releaseSearchItem
+	if err := json.Unmarshal(line, &item); err != nil {
+		return err
+	}
+	records[item.ID] = dumpRecord{Offset: offset, Size: len(line)}
+	entry := dumpSearchEntry{ID: item.ID, Title: item.Title, Barcode: item.Barcode}
+	for _, ac := range item.ArtistCredit {
+		entry.Artists = append(entry.Artists, ac.Name, ac.Artist.Name)
+		entry.ArtistIDs = append(entry.ArtistIDs, ac.Artist.ID)
+	}
+	for _, li := range item.LabelInfo {
+		entry.Labels = append(entry.Labels, li.Label.Name)
+		entry.Catnos = append(entry.Catnos, li.CatalogNumber)
+	}
+	d.index.Releases = append(d.index.Releases, entry)
+	return nil
+}
+
+// Сведения о файлах дампа для проверки актуальности индекса.
+func (d *Dump) fileStats() (map[string]dumpFile, error) {
+	ret := map[string]dumpFile{}
+	for entity, f := range d.files {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		ret[entity] = dumpFile{Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+	}
+	return ret, nil
+}
+
+// Загрузка индекса, если он построен для текущих файлов дампа (с теми же
+// размером и временем изменения каждого файла).
+func (d *Dump) loadIndex() error {
+	f, err := os.Open(filepath.Join(d.dir, dumpIndexFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var index dumpIndex
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&index); err != nil {
+		return err
+	}
+	files, err := d.fileStats()
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(files, index.Files) {
+		return errors.New("dump index is outdated")
+	}
+	d.index = index
+	return nil
+}
+
+func (d *Dump) saveIndex() error {
+	f, err := os.Create(filepath.Join(d.dir, dumpIndexFile))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(&d.index); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Разбор WS/2 URL на тип сущности, ее ID и поисковый запрос.
+func parseWSURL(rawurl string) (entity, id, query string, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+	p := u.Path
+	if i := strings.Index(p, "/ws/2/"); i != -1 {
+		p = p[i+len("/ws/2/"):]
+	}
+	parts := strings.SplitN(strings.Trim(p, "/"), "/", 2)
+	entity = parts[0]
+	if len(parts) == 2 {
+		id = parts[1]
+	}
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if strings.HasPrefix(param, "query=") {
+			query, err = url.PathUnescape(strings.TrimPrefix(param, "query="))
+			return
+		}
+	}
+	return
+}
+
+func containsFold(values []string, v string) bool {
+	for _, val := range values {
+		if strings.EqualFold(val, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package musicbrainz
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+func TestDumpLookupAndSearch(t *testing.T) {
+	const otherID = "00000000-0000-0000-0000-000000000001"
+	dir := t.TempDir()
+	data, err := ioutil.ReadFile(testReleaseJSON)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, "release"), append(data, '\n'), 0644))
+
+	d, err := OpenDump(dir)
+	require.NoError(t, err)
+	defer d.Close()
+
+	m := New("test", "", "", WithDump(d))
+
+	release := md.NewRelease()
+	require.NoError(t, m.releaseByID(testReleaseID, release, m.mapping(nil)))
+	assert.Equal(t, "The Dark Side of the Moon", release.Title)
+
+	var res releaseSearchResult
+	r := md.NewRelease()
+	r.Title = "The Dark Side Of The Moon"
+	r.ActorRoles.Add("Pink Floyd", "performer")
+	r.Publishing.Labels = append(r.Publishing.Labels, md.NewLabel("Harvest", "SHVL 804"))
+	require.NoError(t, m.decodeJSON(searchURL(BaseURL, r, nil), &res))
+	require.Len(t, res.Releases, 1)
+
+	r.Title = "Wish You Were Here"
+	require.NoError(t, m.decodeJSON(searchURL(BaseURL, r, nil), &res))
+	assert.Empty(t, res.Releases)
+
+	var httpErr *HTTPError
+	require.ErrorAs(t, m.releaseByID("unknown", release, m.mapping(nil)), &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	// повторное открытие использует сохраненный индекс
+	d2, err := OpenDump(dir)
+	require.NoError(t, err)
+	defer d2.Close()
+	assert.Len(t, d2.index.Releases, 1)
+
+	// индекс перестраивается после изменения файла дампа
+	d2.Close()
+	data = append(data, '\n')
+	data = append(data, bytes.Replace(data[:len(data)-1], []byte(testReleaseID), []byte(otherID), 1)...)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "release"), append(data, '\n'), 0644))
+	d3, err := OpenDump(dir)
+	require.NoError(t, err)
+	defer d3.Close()
+	assert.Len(t, d3.index.Releases, 2)
+	assert.Contains(t, d3.index.Records["release"], otherID)
+}
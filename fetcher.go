@@ -0,0 +1,144 @@
+package musicbrainz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Fetcher описывает способ получения http-ресурсов клиентом Musicbrainz.
+// Реализации могут добавлять кэширование, запись/воспроизведение ответов,
+// трассировку или подменять сервер Musicbrainz в тестах.
+type Fetcher interface {
+	Fetch(url string, headers map[string]string) (*http.Response, error)
+}
+
+// FetcherFunc позволяет использовать обычную функцию в качестве Fetcher.
+type FetcherFunc func(url string, headers map[string]string) (*http.Response, error)
+
+// Fetch вызывает f(url, headers).
+func (f FetcherFunc) Fetch(url string, headers map[string]string) (*http.Response, error) {
+	return f(url, headers)
+}
+
+// Предельное время выполнения запроса, включая чтение ответа.
+const fetchTimeout = time.Minute
+
+// ErrFetcherClosed возвращается на запросы к остановленному пулу PollerFetcher.
+var ErrFetcherClosed = errors.New("fetcher is closed")
+
+// PollerFetcher выполняет запросы пулом опросчиков, каждый из которых выполняет
+// не более одного запроса за интервал опроса. Используется клиентом Musicbrainz
+// по умолчанию.
+type PollerFetcher struct {
+	mu       sync.Mutex
+	interval time.Duration
+	pollers  []*poller
+	idle     chan *poller
+	ctx      context.Context
+	cancel   context.CancelFunc
+	start    sync.Once
+	stop     sync.Once
+	client   http.Client
+	log      *log.Logger
+}
+
+// Опросчик пула. Таймер создается при запуске пула.
+type poller struct {
+	ticker *time.Ticker
+}
+
+// NewPollerFetcher формирует пул из concurrency опросчиков с указанным
+// интервалом опроса.
+func NewPollerFetcher(interval time.Duration, concurrency int, logger *log.Logger) *PollerFetcher {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	pf := &PollerFetcher{
+		interval: interval,
+		idle:     make(chan *poller, concurrency),
+		client:   http.Client{Timeout: fetchTimeout},
+		log:      logger}
+	pf.ctx, pf.cancel = context.WithCancel(context.Background())
+	for i := 0; i < concurrency; i++ {
+		pf.pollers = append(pf.pollers, &poller{})
+	}
+	return pf
+}
+
+// Start запускает опрос: до запуска запросы ожидают в очереди.
+func (pf *PollerFetcher) Start() {
+	pf.start.Do(func() {
+		pf.mu.Lock()
+		defer pf.mu.Unlock()
+		for _, p := range pf.pollers {
+			p.ticker = time.NewTicker(pf.interval)
+			pf.idle <- p
+		}
+	})
+}
+
+// Close останавливает таймеры опросчиков и прерывает выполняемые запросы.
+// Ожидающие и последующие запросы завершаются ошибкой ErrFetcherClosed.
+func (pf *PollerFetcher) Close() error {
+	pf.stop.Do(func() {
+		pf.mu.Lock()
+		defer pf.mu.Unlock()
+		pf.cancel()
+		for _, p := range pf.pollers {
+			if p.ticker != nil {
+				p.ticker.Stop()
+			}
+		}
+	})
+	return nil
+}
+
+// SetPollingInterval изменяет интервал опроса всех объектов пула.
+func (pf *PollerFetcher) SetPollingInterval(interval time.Duration) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	pf.interval = interval
+	for _, p := range pf.pollers {
+		if p.ticker != nil {
+			p.ticker.Reset(interval)
+		}
+	}
+}
+
+// Concurrency возвращает количество одновременно выполняемых запросов.
+func (pf *PollerFetcher) Concurrency() int {
+	return len(pf.pollers)
+}
+
+// Fetch выполняет "GET" запрос первым освободившимся объектом пула.
+func (pf *PollerFetcher) Fetch(url string, headers map[string]string) (*http.Response, error) {
+	var p *poller
+	select {
+	case p = <-pf.idle:
+	case <-pf.ctx.Done():
+		return nil, ErrFetcherClosed
+	}
+	defer func() { pf.idle <- p }()
+	select {
+	case <-p.ticker.C:
+	case <-pf.ctx.Done():
+		return nil, ErrFetcherClosed
+	}
+	pf.log.Debug(url)
+	req, err := http.NewRequestWithContext(pf.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Add(k, v)
+	}
+	return pf.client.Do(req)
+}
@@ -0,0 +1,107 @@
+// Жанры и теги Musicbrainz (https://musicbrainz.org/doc/Genre).
+
+package musicbrainz
+
+import (
+	"sort"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+	collection "github.com/ytsiuryn/go-collection"
+)
+
+// Ключи дополнительных сведений о жанрах и тегах. Ключи GenresKey и TagsKey
+// используются для релиза (md.Release.Unprocessed) и группы релизов
+// (md.Release.Original.Unprocessed).
+const (
+	GenresKey       = "genres"        // JSON-список Genre
+	TagsKey         = "tags"          // JSON-список Genre для тегов
+	ArtistGenresKey = "artist_genres" // JSON-словарь списков Genre по имени артиста
+	RecordGenresKey = "record_genres" // JSON-список Genre записи (md.Track.Unprocessed)
+	RecordTagsKey   = "record_tags"   // JSON-список Genre для тегов записи (md.Track.Unprocessed)
+)
+
+// Genre описывает жанр или тег Musicbrainz с количеством голосов за него.
+type Genre struct {
+	Name  string `json:"name"`
+	Count int32  `json:"count"`
+}
+
+// GenrePolicy определяет правила выбора жанров записей (md.Record.Genres).
+type GenrePolicy struct {
+	MinVotes int32 `json:"min_votes,omitempty"` // минимальное количество голосов за жанр
+	MaxCount int   `json:"max_count,omitempty"` // максимальное количество жанров (0 - без ограничения)
+	// При отсутствии жанров записи использовать жанры релиза, группы релизов
+	// или исполнителей трека (в указанном порядке).
+	Inherit bool `json:"inherit,omitempty"`
+}
+
+// DefaultGenrePolicy переносит в описание записи все ее жанры без наследования.
+var DefaultGenrePolicy = GenrePolicy{}
+
+// Genres возвращает список жанров или тегов из дополнительных сведений по ключу.
+func Genres(extra collection.StrMap, key string) ([]Genre, error) {
+	var ret []Genre
+	err := extraJSON(extra, key, &ret)
+	return ret, err
+}
+
+// ArtistGenres возвращает жанры артистов релиза по их именам.
+func ArtistGenres(r *md.Release) (map[string][]Genre, error) {
+	var ret map[string][]Genre
+	err := extraJSON(r.Unprocessed, ArtistGenresKey, &ret)
+	return ret, err
+}
+
+type genres []genre
+
+// Genres возвращает жанры (теги), упорядоченные по убыванию количества голосов.
+func (gs genres) Genres() []Genre {
+	var ret []Genre
+	for _, g := range gs {
+		ret = append(ret, Genre{Name: g.Name, Count: g.Count})
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Count > ret[j].Count })
+	return ret
+}
+
+func setGenres(extra collection.StrMap, key string, gs genres) {
+	if len(gs) > 0 {
+		setExtraJSON(extra, key, gs.Genres())
+	}
+}
+
+func addArtistGenres(r *md.Release, name string, gs genres) {
+	if len(gs) == 0 {
+		return
+	}
+	artists, _ := ArtistGenres(r)
+	if artists == nil {
+		artists = map[string][]Genre{}
+	}
+	artists[name] = gs.Genres()
+	setExtraJSON(r.Unprocessed, ArtistGenresKey, artists)
+}
+
+// Select возвращает наименования жанров первого непустого после фильтрации
+// списка кандидатов. Без наследования учитывается только первый список.
+func (p GenrePolicy) Select(candidates ...genres) []string {
+	for i, gs := range candidates {
+		if i > 0 && !p.Inherit {
+			break
+		}
+		var ret []string
+		for _, g := range gs.Genres() {
+			if g.Count < p.MinVotes {
+				continue
+			}
+			if p.MaxCount > 0 && len(ret) == p.MaxCount {
+				break
+			}
+			ret = append(ret, g.Name)
+		}
+		if len(ret) > 0 {
+			return ret
+		}
+	}
+	return nil
+}
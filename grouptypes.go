@@ -0,0 +1,121 @@
+// Типы групп релизов Musicbrainz (https://musicbrainz.org/doc/Release_Group/Type).
+
+package musicbrainz
+
+import (
+	"math"
+	"strings"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+// Ключи дополнительных сведений группы релизов (md.Release.Original.Unprocessed).
+const (
+	PrimaryTypeKey    = "primary_type"    // основной тип группы релизов ("Album", "EP", "Broadcast"...)
+	SecondaryTypesKey = "secondary_types" // JSON-список дополнительных типов ("Live", "Compilation"...)
+)
+
+// Повышение оценки релизов предпочитаемых типов (доля от оценки).
+const PreferredTypeBoost = 0.1
+
+// Соответствие основных типов групп релизов типам релиза.
+var primaryTypes = map[string]md.ReleaseType{
+	"album":  md.ReleaseTypeAlbum,
+	"single": md.ReleaseTypeSingle,
+	"ep":     md.ReleaseTypeMiniAlbum,
+}
+
+// Соответствие дополнительных типов групп релизов признакам релиза. Типы, не
+// имеющие аналога в ds-audiomd ("Soundtrack", "Demo", "Spokenword"...), сохраняются
+// только в исходном виде.
+var secondaryTypes = map[string]func(stub *md.ReleaseStub){
+	"compilation":     func(stub *md.ReleaseStub) { stub.ReleaseRepeat = md.ReleaseRepeatCompilation },
+	"live":            func(stub *md.ReleaseStub) { stub.ReleaseOrigin = md.ReleaseOriginLive },
+	"field recording": func(stub *md.ReleaseStub) { stub.ReleaseOrigin = md.ReleaseOriginFieldRecording },
+	"remix":           func(stub *md.ReleaseStub) { stub.ReleaseRemake = md.ReleaseRemakeRemix },
+}
+
+// Типы группы релизов: признаки релиза и исходные наименования типов.
+func (rgi releaseGroup) addTypes(r *md.Release) {
+	if rgi.PrimaryType != "" {
+		if rt, ok := primaryTypes[strings.ToLower(rgi.PrimaryType)]; ok {
+			r.ReleaseType = rt
+		}
+		r.Original.Unprocessed[PrimaryTypeKey] = rgi.PrimaryType
+	}
+	for _, st := range rgi.SecondaryTypes {
+		if set, ok := secondaryTypes[strings.ToLower(st)]; ok {
+			set(r.ReleaseStub)
+		}
+	}
+	if len(rgi.SecondaryTypes) > 0 {
+		setExtraJSON(r.Original.Unprocessed, SecondaryTypesKey, rgi.SecondaryTypes)
+	}
+}
+
+// ReleaseGroupTypes возвращает основной и дополнительные типы группы релизов
+// в том виде, в котором они указаны в Musicbrainz.
+func ReleaseGroupTypes(r *md.Release) (string, []string) {
+	var secondary []string
+	extraJSON(r.Original.Unprocessed, SecondaryTypesKey, &secondary)
+	return r.Original.Unprocessed[PrimaryTypeKey], secondary
+}
+
+// ReleaseTypeFilter описывает ограничения поиска релизов по типам групп релизов.
+// Типы указываются без учета регистра ("album", "ep", "live", "compilation"...).
+type ReleaseTypeFilter struct {
+	Primary []string `json:"primary,omitempty"` // допустимые основные типы
+	Exclude []string `json:"exclude,omitempty"` // недопустимые дополнительные типы
+	Prefer  []string `json:"prefer,omitempty"`  // предпочитаемые основные или дополнительные типы
+}
+
+// Условия поискового запроса Musicbrainz.
+func (f *ReleaseTypeFilter) query() []string {
+	var ret []string
+	if f == nil {
+		return ret
+	}
+	if len(f.Primary) > 0 {
+		var p []string
+		for _, pt := range f.Primary {
+			p = append(p, queryParam("primarytype", pt))
+		}
+		ret = append(ret, "("+strings.Join(p, " OR ")+")")
+	}
+	for _, st := range f.Exclude {
+		ret = append(ret, "NOT "+queryParam("secondarytype", st))
+	}
+	return ret
+}
+
+// Accept проверяет соответствие типов группы релизов ограничениям. Релизы с
+// неизвестными типами допустимы.
+func (f *ReleaseTypeFilter) Accept(r *md.Release) bool {
+	if f == nil {
+		return true
+	}
+	primary, secondary := ReleaseGroupTypes(r)
+	if primary != "" && len(f.Primary) > 0 && !containsFold(f.Primary, primary) {
+		return false
+	}
+	for _, st := range secondary {
+		if containsFold(f.Exclude, st) {
+			return false
+		}
+	}
+	return true
+}
+
+// Boost повышает оценку релиза предпочитаемого типа.
+func (f *ReleaseTypeFilter) Boost(r *md.Release, score float64) float64 {
+	if f == nil || len(f.Prefer) == 0 {
+		return score
+	}
+	primary, secondary := ReleaseGroupTypes(r)
+	for _, t := range append(secondary, primary) {
+		if t != "" && containsFold(f.Prefer, t) {
+			return math.Min(1., score*(1+PreferredTypeBoost))
+		}
+	}
+	return score
+}
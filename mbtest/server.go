@@ -0,0 +1,233 @@
+// Package mbtest реализует тестовый http-сервер, имитирующий WS/2 API Musicbrainz
+// и Cover Art Archive на основе записанных ответов.
+//
+// Сервер позволяет моделировать ошибки, задержки ответа и отказы из-за превышения
+// частоты запросов (503 с заголовком Retry-After).
+package mbtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Путь WS/2 API относительно адреса сервера.
+const wsPath = "/ws/2/"
+
+// Ответ на поиск при отсутствии записанных результатов.
+const emptySearch = `{"created":"","count":0,"offset":0}`
+
+type failure struct {
+	statusCode int
+	retryAfter time.Duration
+}
+
+// Server описывает тестовый сервер Musicbrainz.
+type Server struct {
+	*httptest.Server
+	mu        sync.Mutex
+	resources map[string][]byte
+	searches  map[string][]byte
+	browses   map[string][]byte
+	failures  []failure
+	latency   time.Duration
+	requests  []string
+	throttled int
+	remaining int
+	window    time.Duration
+}
+
+// NewServer запускает тестовый сервер без записанных ответов.
+func NewServer() *Server {
+	s := &Server{
+		resources: map[string][]byte{},
+		searches:  map[string][]byte{},
+		browses:   map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// WSURL возвращает адрес WS/2 API тестового сервера.
+func (s *Server) WSURL() string {
+	return s.URL + wsPath
+}
+
+// ImgURL возвращает адрес Cover Art Archive тестового сервера.
+func (s *Server) ImgURL() string {
+	return s.URL
+}
+
+// Add регистрирует ответ на запрос сущности по ID (например, "release", "<mbid>").
+func (s *Server) Add(entity, id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[wsPath+entity+"/"+id] = data
+}
+
+// AddFile регистрирует ответ на запрос сущности по ID из файла.
+func (s *Server) AddFile(entity, id, fn string) error {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	s.Add(entity, id, data)
+	return nil
+}
+
+// AddCover регистрирует ответ Cover Art Archive для сущности.
+func (s *Server) AddCover(entity, id string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources["/"+entity+"/"+id] = data
+}
+
+// SetSearch регистрирует ответ на любой поисковый запрос по типу сущности.
+func (s *Server) SetSearch(entity string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.searches[entity] = data
+}
+
+// SetSearchFile регистрирует ответ на поисковый запрос из файла.
+func (s *Server) SetSearchFile(entity, fn string) error {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return err
+	}
+	s.SetSearch(entity, data)
+	return nil
+}
+
+// AddBrowse регистрирует страницу ответа на просмотр сущностей (например,
+// "release-group") с указанным смещением (параметр offset запроса).
+func (s *Server) AddBrowse(entity string, offset int, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.browses[entity+"@"+strconv.Itoa(offset)] = data
+}
+
+// LoadDir регистрирует ответы из каталога, содержащего подкаталоги сущностей с
+// файлами "<mbid>.json" и необязательным файлом результатов поиска "search.json".
+func (s *Server) LoadDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		entity := filepath.Base(filepath.Dir(path))
+		id := strings.TrimSuffix(info.Name(), ".json")
+		if id == "search" {
+			return s.SetSearchFile(entity, path)
+		}
+		return s.AddFile(entity, id, path)
+	})
+}
+
+// FailNext задает код ошибки для n следующих запросов.
+func (s *Server) FailNext(n, statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, failure{statusCode: statusCode})
+	}
+}
+
+// ThrottleNext отклоняет n следующих запросов с кодом 503 и заголовком Retry-After.
+func (s *Server) ThrottleNext(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures,
+			failure{statusCode: http.StatusServiceUnavailable, retryAfter: retryAfter})
+	}
+}
+
+// SetLatency задает задержку перед каждым ответом.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetRateLimit добавляет в успешные ответы заголовки X-RateLimit-Remaining и
+// X-RateLimit-Reset (через window после ответа). При remaining = 0 заголовки
+// не передаются.
+func (s *Server) SetRateLimit(remaining int, window time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remaining = remaining
+	s.window = window
+}
+
+// Throttled возвращает количество запросов, отклоненных с кодом 503 или 429.
+func (s *Server) Throttled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.throttled
+}
+
+// Requests возвращает URI всех полученных сервером запросов.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	latency := s.latency
+	remaining, window := s.remaining, s.window
+	var f *failure
+	if len(s.failures) > 0 {
+		f = &s.failures[0]
+		s.failures = s.failures[1:]
+		if f.statusCode == http.StatusServiceUnavailable || f.statusCode == http.StatusTooManyRequests {
+			s.throttled++
+		}
+	}
+	data, ok := s.resources[r.URL.Path]
+	if !ok && strings.HasPrefix(r.URL.Path, wsPath) && r.URL.Query().Get("query") != "" {
+		entity := strings.Trim(strings.TrimPrefix(r.URL.Path, wsPath), "/")
+		if data, ok = s.searches[entity]; !ok {
+			data, ok = []byte(emptySearch), true
+		}
+	}
+	if !ok && strings.HasPrefix(r.URL.Path, wsPath) && r.URL.Query().Get("query") == "" {
+		entity := strings.Trim(strings.TrimPrefix(r.URL.Path, wsPath), "/")
+		offset := r.URL.Query().Get("offset")
+		if offset == "" {
+			offset = "0"
+		}
+		data, ok = s.browses[entity+"@"+offset]
+	}
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+
+	if f != nil {
+		if f.statusCode == http.StatusServiceUnavailable || f.statusCode == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.retryAfter/time.Second)))
+		}
+		http.Error(w, http.StatusText(f.statusCode), f.statusCode)
+		return
+	}
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"Not Found"}`)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if remaining > 0 {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(window).Unix(), 10))
+	}
+	w.Write(data)
+}
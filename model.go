@@ -1,13 +1,16 @@
 package musicbrainz
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
 	md "github.com/ytsiuryn/ds-audiomd"
 	collection "github.com/ytsiuryn/go-collection"
 	intutils "github.com/ytsiuryn/go-intutils"
-	tp "github.com/ytsiuryn/go-stringutils"
 )
 
 // coverarchive.org structure:
@@ -36,8 +39,10 @@ type imageInfo struct {
 // }
 
 type label struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	LabelCode int32   `json:"label-code"`
+	Aliases   aliases `json:"aliases"`
 }
 
 type labelInfo struct {
@@ -65,10 +70,15 @@ type textRepresentation struct {
 }
 
 type trackArtist struct {
-	// Disambiguation string `json:"disambiguation"`
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	SortName string `json:"sort-name"`
+	Disambiguation string  `json:"disambiguation"`
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	SortName       string  `json:"sort-name"`
+	Genres         genres  `json:"genres"`
+	Tags           genres  `json:"tags"`
+	Rating         *rating `json:"rating"`
+	UserRating     *rating `json:"user-rating"`
+	Aliases        aliases `json:"aliases"`
 }
 
 type artistCredit struct {
@@ -77,26 +87,54 @@ type artistCredit struct {
 	Name       string      `json:"name"`
 }
 
-type attributeValues struct {
-}
-
-type attributeIDs struct {
+// artistCredits описывает полное указание артистов релиза или трека.
+type artistCredits []artistCredit
+
+// String возвращает указание артистов в том виде, в котором оно приведено в
+// релизе (например, "Simon & Garfunkel" или "Santana feat. Rob Thomas").
+func (acs artistCredits) String() string {
+	var b strings.Builder
+	for _, ac := range acs {
+		name := ac.Name
+		if name == "" {
+			name = ac.Artist.Name
+		}
+		b.WriteString(name)
+		b.WriteString(ac.JoinPhrase)
+	}
+	return b.String()
 }
 
 type relation struct {
-	AttributeValues attributeValues `json:"attribute-values"`
-	Type            string          `json:"type"`
-	TargetType      string          `json:"target-type"`
-	Begin           string          `json:"begin"`
-	Ended           bool            `json:"ended"`
-	Artist          trackArtist     `json:"artist"`
+	AttributeValues  map[string]string `json:"attribute-values"`
+	AttributeCredits map[string]string `json:"attribute-credits"`
+	Type             string            `json:"type"`
+	TargetType       string            `json:"target-type"`
+	Begin            string            `json:"begin"`
+	Ended            bool              `json:"ended"`
+	Artist           trackArtist       `json:"artist"`
 	// TypeID          string          `json:"type-id"`
-	End          string       `json:"end"`
-	SourceCredit string       `json:"source-credit"`
-	AttributeIDs attributeIDs `json:"attribute-ids"`
-	TargetCredit string       `json:"target-credit"`
-	Direction    string       `json:"direction"`
-	Attributes   []string     `json:"attributes"`
+	End          string            `json:"end"`
+	SourceCredit string            `json:"source-credit"`
+	AttributeIDs map[string]string `json:"attribute-ids"`
+	TargetCredit string            `json:"target-credit"`
+	Direction    string            `json:"direction"`
+	Attributes   []string          `json:"attributes"`
+	URL          *urlTarget        `json:"url"`
+	Work         *work             `json:"work"`
+	Release      *relatedRelease   `json:"release"`
+}
+
+type work struct {
+	ID             string  `json:"id"`
+	Title          string  `json:"title"`
+	Disambiguation string  `json:"disambiguation"`
+	Aliases        aliases `json:"aliases"`
+}
+
+type urlTarget struct {
+	ID       string `json:"id"`
+	Resource string `json:"resource"`
 }
 
 type genre struct {
@@ -105,23 +143,29 @@ type genre struct {
 }
 
 type recording struct {
-	Title     string     `json:"title"`
-	Relations []relation `json:"relations"`
-	// Disambiguation string     `json:"disambiguation"`
-	ID     string  `json:"id"`
-	Length int32   `json:"length"`
-	Genres []genre `json:"genres"`
-	// ArtistCredits  []artistCredit `json:"artist-credit"`
+	Title          string        `json:"title"`
+	Relations      []relation    `json:"relations"`
+	Disambiguation string        `json:"disambiguation"`
+	Video          bool          `json:"video"`
+	ID             string        `json:"id"`
+	Length         int32         `json:"length"`
+	Genres         genres        `json:"genres"`
+	Tags           genres        `json:"tags"`
+	Rating         *rating       `json:"rating"`
+	UserRating     *rating       `json:"user-rating"`
+	UserTags       userTags      `json:"user-tags"`
+	ArtistCredits  artistCredits `json:"artist-credit"`
+	ISRCs          []string      `json:"isrcs"`
 }
 
 type track struct {
-	Number    string    `json:"number"`
-	Recording recording `json:"recording"`
-	Title     string    `json:"title"`
-	ID        string    `json:"id"`
-	Length    int32     `json:"length"`
-	Position  int32     `json:"position"`
-	// ArtistCredits []artistCredit `json:"artist-credit"`
+	Number        string        `json:"number"`
+	Recording     recording     `json:"recording"`
+	Title         string        `json:"title"`
+	ID            string        `json:"id"`
+	Length        int32         `json:"length"`
+	Position      int32         `json:"position"`
+	ArtistCredits artistCredits `json:"artist-credit"`
 }
 
 type mediaFullInfo struct {
@@ -138,16 +182,37 @@ type coverInfo struct {
 	ReleaseURL string      `json:"release"`
 }
 
+type area struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	ISOCodes []string `json:"iso-3166-1-codes"`
+}
+
+type releaseEvent struct {
+	Date string `json:"date"`
+	Area *area  `json:"area"`
+}
+
 type releaseGroup struct {
-	Annotation       string `json:"annotation,omitempty"`
-	FirstReleaseDate string `json:"first-release-date"`
-	Title            string `json:"title"`
-	ID               string `json:"ID"`
+	Annotation       string        `json:"annotation,omitempty"`
+	FirstReleaseDate string        `json:"first-release-date"`
+	Title            string        `json:"title"`
+	ID               string        `json:"ID"`
+	PrimaryType      string        `json:"primary-type"`
+	SecondaryTypes   []string      `json:"secondary-types"`
+	Genres           genres        `json:"genres"`
+	Tags             genres        `json:"tags"`
+	Rating           *rating       `json:"rating"`
+	UserRating       *rating       `json:"user-rating"`
+	UserTags         userTags      `json:"user-tags"`
+	Aliases          aliases       `json:"aliases"`
+	Relations        []relation    `json:"relations"`
+	ArtistCredit     artistCredits `json:"artist-credit"`
 }
 
 type releaseInfo struct {
 	Asin               string             `json:"asin"`
-	ArtistCredit       []artistCredit     `json:"artist-credit"`
+	ArtistCredit       artistCredits      `json:"artist-credit"`
 	Barcode            string             `json:"barcode"`
 	Title              string             `json:"title"`
 	Media              []mediaFullInfo    `json:"media"`
@@ -158,24 +223,28 @@ type releaseInfo struct {
 	Date               string             `json:"date"`
 	Country            string             `json:"country"`
 	LabelInfo          []labelInfo        `json:"label-info"`
+	ReleaseEvents      []releaseEvent     `json:"release-events"`
 	ReleaseGroup       releaseGroup       `json:"release-group"`
 	Annotation         string             `json:"annotation"`
 	TextRepresentation textRepresentation `json:"text-representation"`
+	Genres             genres             `json:"genres"`
+	Tags               genres             `json:"tags"`
+	UserTags           userTags           `json:"user-tags"`
 }
 
 type releaseSearchItem struct {
 	ID    string `json:"id"`
 	Score int32  `json:"score"`
 	// Count     int32  `json:"count"`
-	Title        string         `json:"title"`
-	Status       string         `json:"status"`
-	Packaging    string         `json:"packaging"`
-	ArtistCredit []artistCredit `json:"artist-credit"`
-	// ReleaseGroup ShortReleaseGroup `json:"release-group"`
-	Date      string      `json:"date"`
-	Country   string      `json:"country"`
-	Barcode   string      `json:"barcode"`
-	LabelInfo []labelInfo `json:"label-info"`
+	Title        string        `json:"title"`
+	Status       string        `json:"status"`
+	Packaging    string        `json:"packaging"`
+	ArtistCredit artistCredits `json:"artist-credit"`
+	ReleaseGroup releaseGroup  `json:"release-group"`
+	Date         string        `json:"date"`
+	Country      string        `json:"country"`
+	Barcode      string        `json:"barcode"`
+	LabelInfo    []labelInfo   `json:"label-info"`
 	// TrackCount int32       `json:"track-count"`
 	Media              []media            `json:"media"`
 	TextRepresentation textRepresentation `json:"text-representation"`
@@ -188,8 +257,167 @@ type releaseSearchResult struct {
 	Releases []releaseSearchItem `json:"releases"`
 }
 
+// Ключи дополнительных сведений релиза (md.Release.Unprocessed), не имеющих
+// соответствия в структурах ds-audiomd.
+const (
+	// упаковка издания ("Jewel Case", "Gatefold Cover"...): md.Publishing
+	// содержит только лейблы и идентификаторы издания, поля для упаковки нет
+	PackagingKey         = "packaging"
+	ReleaseStatusKey     = "release_status"      // статус Musicbrainz, не имеющий аналога в md.ReleaseStatus
+	ReleaseEventsKey     = "release_events"      // JSON-список ReleaseEvent
+	AlbumArtistsKey      = "album_artists"       // JSON-список Artist исполнителей релиза
+	AlbumArtistCreditKey = "album_artist_credit" // полное указание исполнителей релиза
+	ReleaseArtistsKey    = "release_artists"     // JSON-список Artist участников релиза
+	ReleaseCreditsKey    = "release_credits"     // JSON-список RoleCredit участников релиза
+	URLsKey              = "urls"                // JSON-словарь ссылок релиза по типу связи
+	LabelCodesKey        = "label_codes"         // JSON-словарь кодов лейблов ("LC-01305") по наименованию
+	CatalogNumbersKey    = "catalog_numbers"     // JSON-словарь каталожных номеров лейблов, имеющих несколько номеров
+)
+
+// Ключи дополнительных сведений трека (md.Track.Unprocessed).
+const (
+	ArtistsKey              = "artists"               // JSON-список Artist участников записи
+	ArtistCreditKey         = "artist_credit"         // полное указание исполнителей трека
+	CreditsKey              = "credits"               // JSON-список RoleCredit участников записи
+	TrackNumberKey          = "track_number"          // номер трека, указанный в релизе ("A1"), если отличается от позиции
+	RecordDisambiguationKey = "record_disambiguation" // уточнение записи ("live", "original stereo mix"...)
+	VideoKey                = "video"                 // "true" для видеозаписи
+)
+
+// Artist описывает сведения об артисте Musicbrainz, не вошедшие в md.ActorsIDs.
+// Актор в коллекциях md.ActorsIDs и md.ActorRoles указывается по имени артиста
+// в Musicbrainz, а имя, под которым он указан в релизе, сохраняется в CreditedAs.
+type Artist struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	SortName       string `json:"sort_name,omitempty"`
+	Disambiguation string `json:"disambiguation,omitempty"`
+	CreditedAs     string `json:"credited_as,omitempty"`
+}
+
+// Artists возвращает список артистов из дополнительных сведений релиза или трека
+// по ключу AlbumArtistsKey или ArtistsKey.
+func Artists(extra collection.StrMap, key string) ([]Artist, error) {
+	var ret []Artist
+	err := extraJSON(extra, key, &ret)
+	return ret, err
+}
+
+func addArtist(extra collection.StrMap, key string, a Artist) {
+	artists, _ := Artists(extra, key)
+	for _, artist := range artists {
+		if artist == a {
+			return
+		}
+	}
+	setExtraJSON(extra, key, append(artists, a))
+}
+
+// RoleCredit описывает участие артиста в релизе или записи по связи Musicbrainz
+// с подробностями, не вошедшими в md.ActorRoles: период участия, имя, под которым
+// указан артист, и значения атрибутов связи.
+type RoleCredit struct {
+	ArtistID   string   `json:"artist_id,omitempty"`
+	Artist     string   `json:"artist"`
+	CreditedAs string   `json:"credited_as,omitempty"`
+	Type       string   `json:"type"`
+	Roles      []string `json:"roles"`
+	Begin      string   `json:"begin,omitempty"`
+	End        string   `json:"end,omitempty"`
+	Ended      bool     `json:"ended,omitempty"`
+	// Значения атрибутов (например, "task": "recording assistant").
+	Values map[string]string `json:"attribute_values,omitempty"`
+	// Наименования атрибутов в релизе (например, "bass guitar": "bass").
+	CreditedAttributes map[string]string `json:"attribute_credits,omitempty"`
+	// Идентификаторы атрибутов Musicbrainz.
+	AttributeIDs map[string]string `json:"attribute_ids,omitempty"`
+}
+
+// RoleCredits возвращает подробности участия артистов из дополнительных сведений
+// релиза или трека по ключу ReleaseCreditsKey или CreditsKey.
+func RoleCredits(extra collection.StrMap, key string) ([]RoleCredit, error) {
+	var ret []RoleCredit
+	err := extraJSON(extra, key, &ret)
+	return ret, err
+}
+
+func addRoleCredit(extra collection.StrMap, key string, rc RoleCredit) {
+	credits, _ := RoleCredits(extra, key)
+	for _, credit := range credits {
+		if reflect.DeepEqual(credit, rc) {
+			return
+		}
+	}
+	setExtraJSON(extra, key, append(credits, rc))
+}
+
+// Разбор значения дополнительных сведений в формате JSON.
+func extraJSON(extra collection.StrMap, key string, out interface{}) error {
+	if v, ok := extra[key]; ok {
+		return json.Unmarshal([]byte(v), out)
+	}
+	return nil
+}
+
+// Сохранение значения в дополнительных сведениях в формате JSON.
+func setExtraJSON(extra collection.StrMap, key string, v interface{}) {
+	if data, err := json.Marshal(v); err == nil {
+		extra[key] = string(data)
+	}
+}
+
+// ReleaseEvent описывает выпуск издания на определенной территории.
+type ReleaseEvent struct {
+	Date    string `json:"date,omitempty"`
+	Country string `json:"country,omitempty"` // код ISO 3166-1 или код Musicbrainz ("XW", "XE")
+	Area    string `json:"area,omitempty"`
+	AreaID  string `json:"area_id,omitempty"`
+}
+
+// ReleaseEvents возвращает сведения о выпусках издания из дополнительных сведений релиза.
+func ReleaseEvents(r *md.Release) ([]ReleaseEvent, error) {
+	var ret []ReleaseEvent
+	err := extraJSON(r.Unprocessed, ReleaseEventsKey, &ret)
+	return ret, err
+}
+
+// Соответствие статусов релизов Musicbrainz значениям md.ReleaseStatus.
+var releaseStatuses = map[string]md.ReleaseStatus{
+	"official":  md.ReleaseStatusOfficial,
+	"promotion": md.ReleaseStatusPromotion,
+	"bootleg":   md.ReleaseStatusBootleg,
+}
+
+// Статус релиза. Статусы без аналога в md.ReleaseStatus ("Pseudo-Release",
+// "Withdrawn", "Cancelled"...) сохраняются в дополнительных сведениях релиза.
+func decodeReleaseStatus(r *md.Release, status string) {
+	if status == "" {
+		return
+	}
+	if rs, ok := releaseStatuses[strings.ToLower(status)]; ok {
+		r.ReleaseStatus = rs
+	} else {
+		r.Unprocessed[ReleaseStatusKey] = status
+	}
+}
+
+// mapping описывает параметры преобразования данных Musicbrainz в общий формат.
+type mapping struct {
+	roles  RoleMapping
+	genres GenrePolicy
+	locale string // локаль наименований ("ru", "en"...)
+	script string // письменность списка треков ("Latn", "Jpan"...)
+	notes  NotesFormat
+}
+
+// Параметры преобразования по умолчанию.
+func newMapping() *mapping {
+	return &mapping{roles: DefaultRoles, genres: DefaultGenrePolicy, notes: NotesText}
+}
+
 // Release converts data to common album format.
-func (ri *releaseInfo) Release(r *md.Release) {
+func (ri *releaseInfo) Release(r *md.Release, mp *mapping) {
+	ri.localize(r, mp.locale)
 	r.Title = ri.Title
 	// album.Record
 	r.Country = ri.Country
@@ -201,43 +429,102 @@ func (ri *releaseInfo) Release(r *md.Release) {
 	if len(ri.Asin) > 0 {
 		r.IDs[md.Asin] = ri.Asin
 	}
-	r.Notes = ri.Annotation
-	for _, li := range ri.LabelInfo {
-		lbl := li.NewLabel()
-		if !collection.Contains(lbl, r.Publishing.Labels) {
-			r.Publishing.Labels = append(r.Publishing.Labels, lbl)
-		}
-	}
-	r.Year = tp.NaiveStringToInt(ri.Date)
-	ri.ReleaseGroup.ReleaseGroup(r)
+	r.Notes = RenderAnnotation(ri.Annotation, mp.notes)
+	addLabels(r, ri.LabelInfo)
+	setReleaseDate(r.ReleaseStub, ri.Date)
+	ri.ReleaseGroup.ReleaseGroup(r, mp)
 	for i, mediaDisc := range ri.Media {
-		disc := r.Disc(i + 1)
-		for _, track := range mediaDisc.Tracks {
-			tr := track.Track(disc)
+		disc := discByNumber(r, mediaDisc.number(i))
+		for j, tr := range mediaDisc.Disc(disc, mp) {
+			track := mediaDisc.Tracks[j]
 			tr.Composition.Lyrics.Language = ri.TextRepresentation.Language
-			for _, genre := range track.Recording.Genres {
-				tr.Record.Genres = append(tr.Record.Genres, genre.Name)
+			credits := track.Credits(ri.ArtistCredit)
+			credits.AddTrackPerformers(tr)
+			var artistGenres genres
+			for _, ac := range credits {
+				addArtistGenres(r, ac.Artist.Name, ac.Artist.Genres)
+				addArtistRating(r, ac.Artist)
+				artistGenres = append(artistGenres, ac.Artist.Genres...)
 			}
+			tr.Record.Genres = mp.genres.Select(
+				track.Recording.Genres, ri.Genres, ri.ReleaseGroup.Genres, artistGenres)
+			setGenres(tr.Unprocessed, RecordGenresKey, track.Recording.Genres)
+			setGenres(tr.Unprocessed, RecordTagsKey, track.Recording.Tags)
+			setRating(tr.Unprocessed, RecordRatingKey, track.Recording.Rating, track.Recording.UserRating)
+			setUserTags(tr.Unprocessed, RecordUserTagsKey, track.Recording.UserTags)
 			r.Tracks = append(r.Tracks, tr)
 			r.TotalTracks++
 		}
-		r.TotalDiscs++
 	}
+	sort.SliceStable(r.Discs, func(i, j int) bool { return r.Discs[i].Number < r.Discs[j].Number })
+	r.TotalDiscs = len(r.Discs)
+	ri.ArtistCredit.AddPerformers(r)
 	for _, ac := range ri.ArtistCredit {
-		ac.AddPerformer(r)
+		addArtistGenres(r, ac.Artist.Name, ac.Artist.Genres)
+		addArtistRating(r, ac.Artist)
+	}
+	setGenres(r.Unprocessed, GenresKey, ri.Genres)
+	setGenres(r.Unprocessed, TagsKey, ri.Tags)
+	setUserTags(r.Unprocessed, UserTagsKey, ri.UserTags)
+	decodeReleaseStatus(r, ri.Status)
+	if ri.Packaging != "" {
+		r.Unprocessed[PackagingKey] = ri.Packaging
+	}
+	if ri.TextRepresentation.Script != "" {
+		r.Unprocessed[ScriptKey] = ri.TextRepresentation.Script
+	}
+	ri.addReleaseEvents(r)
+	for _, rel := range ri.Relations {
+		rel.AddToRelease(r.ReleaseStub, md.DiscogsReleaseID, mp.roles)
 	}
-	r.ReleaseStatus.Decode(ri.Status)
-	// ri.Packaging
 }
 
-func (rgi releaseGroup) ReleaseGroup(r *md.Release) {
+func (ri *releaseInfo) addReleaseEvents(r *md.Release) {
+	var events []ReleaseEvent
+	for _, re := range ri.ReleaseEvents {
+		event := ReleaseEvent{Date: re.Date}
+		if re.Area != nil {
+			event.Area = re.Area.Name
+			event.AreaID = re.Area.ID
+			if len(re.Area.ISOCodes) > 0 {
+				event.Country = re.Area.ISOCodes[0]
+			}
+		}
+		events = append(events, event)
+	}
+	if len(events) > 0 {
+		setExtraJSON(r.Unprocessed, ReleaseEventsKey, events)
+	}
+}
+
+func (rgi releaseGroup) ReleaseGroup(r *md.Release, mp *mapping) {
 	r.Original.IDs[md.MusicbrainzReleaseGroupID] = rgi.ID
-	r.Original.Year = tp.NaiveStringToInt(strings.SplitN(rgi.FirstReleaseDate, "-", 3)[0])
+	r.Original.Title = rgi.Title
+	setReleaseDate(r.Original, rgi.FirstReleaseDate)
+	rgi.addTypes(r)
+	setGenres(r.Original.Unprocessed, GenresKey, rgi.Genres)
+	setGenres(r.Original.Unprocessed, TagsKey, rgi.Tags)
+	setRating(r.Original.Unprocessed, RatingKey, rgi.Rating, rgi.UserRating)
+	setUserTags(r.Original.Unprocessed, UserTagsKey, rgi.UserTags)
 	if len(rgi.Annotation) > 0 {
-		r.Original.Notes = rgi.Annotation
+		r.Original.Notes = RenderAnnotation(rgi.Annotation, mp.notes)
+	}
+	for _, rel := range rgi.Relations {
+		rel.AddToRelease(r.Original, md.DiscogsMasterID, mp.roles)
 	}
 }
 
+// Release возвращает релиз, представляющий группу релизов, полученную при
+// просмотре групп релизов артиста. Заполняются только сведения оригинала и
+// исполнители.
+func (rgi releaseGroup) Release(mp *mapping) *md.Release {
+	r := md.NewRelease()
+	r.Title = rgi.Title
+	rgi.ReleaseGroup(r, mp)
+	rgi.ArtistCredit.AddPerformers(r)
+	return r
+}
+
 func (rs releaseSearchResult) Search() []*md.Release {
 	var ret []*md.Release
 	var r *md.Release
@@ -256,13 +543,16 @@ func (si releaseSearchItem) Release() *md.Release {
 	if len(si.Barcode) > 0 {
 		r.Publishing.IDs[md.PublishingBarcode] = si.Barcode
 	}
-	for _, li := range si.LabelInfo {
-		r.Publishing.Labels = append(r.Publishing.Labels, li.NewLabel())
+	addLabels(r, si.LabelInfo)
+	if si.ReleaseGroup.ID != "" {
+		r.Original.IDs[md.MusicbrainzReleaseGroupID] = si.ReleaseGroup.ID
 	}
-	for _, ac := range si.ArtistCredit {
-		ac.AddPerformer(r)
+	si.ReleaseGroup.addTypes(r)
+	si.ArtistCredit.AddPerformers(r)
+	decodeReleaseStatus(r, si.Status)
+	if si.Packaging != "" {
+		r.Unprocessed[PackagingKey] = si.Packaging
 	}
-	r.ReleaseStatus.Decode(si.Status)
 	return r
 }
 
@@ -285,69 +575,417 @@ func (ci coverInfo) Cover() *md.PictureInAudio {
 	return nil
 }
 
+// Специальный лейбл Musicbrainz для релизов без лейбла и каталожный номер
+// релиза без номера.
+const (
+	noLabel = "[no label]"
+	noCatno = "[none]"
+)
+
+// NewLabel возвращает описание лейбла. Специальный лейбл "[no label]" и
+// каталожный номер "[none]" означают их отсутствие.
 func (li labelInfo) NewLabel() *md.Label {
-	lbl := md.NewLabel(li.Label.Name, li.CatalogNumber)
-	if li.CatalogNumber != "" {
+	name := li.Label.Name
+	if strings.EqualFold(name, noLabel) {
+		name = ""
+	}
+	catno := li.CatalogNumber
+	if strings.EqualFold(catno, noCatno) {
+		catno = ""
+	}
+	lbl := md.NewLabel(name, catno)
+	if name != "" && li.Label.ID != "" {
 		lbl.IDs[md.MusicbrainzLabelID] = li.Label.ID
 	}
 	return lbl
 }
 
+// Добавление лейблов релиза. Сведения об одном лейбле с несколькими каталожными
+// номерами объединяются: в описании лейбла остается первый номер, а полный
+// список номеров сохраняется по ключу CatalogNumbersKey.
+func addLabels(r *md.Release, lis []labelInfo) {
+	catnos := map[string][]string{}
+	codes := map[string]string{}
+	for _, li := range lis {
+		lbl := li.NewLabel()
+		if lbl.Label == "" && lbl.Catno == "" {
+			continue
+		}
+		if existing := findLabel(r.Publishing.Labels, lbl); existing == nil {
+			r.Publishing.Labels = append(r.Publishing.Labels, lbl)
+		} else if existing.Catno == "" {
+			existing.Catno = lbl.Catno
+		}
+		if lbl.Catno != "" && !collection.ContainsStr(lbl.Catno, catnos[lbl.Label]) {
+			catnos[lbl.Label] = append(catnos[lbl.Label], lbl.Catno)
+		}
+		if lbl.Label != "" && li.Label.LabelCode > 0 {
+			codes[lbl.Label] = fmt.Sprintf("LC-%05d", li.Label.LabelCode)
+		}
+	}
+	for name, list := range catnos {
+		if len(list) < 2 {
+			delete(catnos, name)
+		}
+	}
+	if len(catnos) > 0 {
+		setExtraJSON(r.Unprocessed, CatalogNumbersKey, catnos)
+	}
+	if len(codes) > 0 {
+		setExtraJSON(r.Unprocessed, LabelCodesKey, codes)
+	}
+}
+
+// LabelCodes возвращает коды лейблов релиза по их наименованиям.
+func LabelCodes(r *md.Release) (map[string]string, error) {
+	var ret map[string]string
+	err := extraJSON(r.Unprocessed, LabelCodesKey, &ret)
+	return ret, err
+}
+
+// CatalogNumbers возвращает каталожные номера лейблов, имеющих в релизе
+// несколько номеров.
+func CatalogNumbers(r *md.Release) (map[string][]string, error) {
+	var ret map[string][]string
+	err := extraJSON(r.Unprocessed, CatalogNumbersKey, &ret)
+	return ret, err
+}
+
+// Поиск лейбла по идентификатору Musicbrainz или наименованию.
+func findLabel(labels []*md.Label, lbl *md.Label) *md.Label {
+	for _, l := range labels {
+		id, otherID := l.IDs[md.MusicbrainzLabelID], lbl.IDs[md.MusicbrainzLabelID]
+		if id != "" && otherID != "" {
+			if id == otherID {
+				return l
+			}
+			continue
+		}
+		if strings.EqualFold(l.Label, lbl.Label) {
+			return l
+		}
+	}
+	return nil
+}
+
+// AddPerformers добавляет артистов в исполнители релиза и сохраняет полное
+// указание исполнителей.
+func (acs artistCredits) AddPerformers(r *md.Release) {
+	for _, ac := range acs {
+		ac.AddPerformer(r)
+	}
+	if credit := acs.String(); credit != "" {
+		r.Unprocessed[AlbumArtistCreditKey] = credit
+	}
+}
+
+// AddTrackPerformers добавляет артистов в исполнители записи трека и сохраняет
+// полное указание исполнителей трека.
+func (acs artistCredits) AddTrackPerformers(track *md.Track) {
+	for _, ac := range acs {
+		name := ac.Artist.Name
+		if name == "" {
+			name = ac.Name
+		}
+		if name == "" {
+			continue
+		}
+		if ac.Artist.ID != "" {
+			track.Actors.Add(name, md.MusicbrainzArtistID, ac.Artist.ID)
+		}
+		track.Record.ActorRoles.Add(name, "performer")
+		addArtist(track.Unprocessed, ArtistsKey, ac.Artist.Info(ac.Name))
+	}
+	if credit := acs.String(); credit != "" {
+		track.Unprocessed[ArtistCreditKey] = credit
+	}
+}
+
+// AddPerformer добавляет артиста в исполнители релиза.
 func (ac artistCredit) AddPerformer(r *md.Release) {
-	if ac.Name != "" {
-		r.Actors.Add(ac.Name, md.DiscogsArtistID, ac.Artist.ID)
-		r.ActorRoles.Add(ac.Name, "performer")
+	name := ac.Artist.Name
+	if name == "" {
+		name = ac.Name
+	}
+	if name == "" {
+		return
+	}
+	if ac.Artist.ID != "" {
+		r.Actors.Add(name, md.MusicbrainzArtistID, ac.Artist.ID)
+	}
+	r.ActorRoles.Add(name, "performer")
+	addArtist(r.Unprocessed, AlbumArtistsKey, ac.Artist.Info(ac.Name))
+}
+
+// Info возвращает сведения об артисте с учетом имени, под которым он указан.
+func (ta trackArtist) Info(creditedAs string) Artist {
+	a := Artist{
+		ID:             ta.ID,
+		Name:           ta.Name,
+		SortName:       ta.SortName,
+		Disambiguation: ta.Disambiguation,
+	}
+	if creditedAs != ta.Name {
+		a.CreditedAs = creditedAs
+	}
+	return a
+}
+
+// Номер диска: позиция носителя в релизе или порядковый номер i+1, если
+// позиция не указана.
+func (mi mediaFullInfo) number(i int) int {
+	if mi.Position > 0 {
+		return int(mi.Position)
 	}
+	return i + 1
 }
 
-// TODO: добавить информацию о диске.
-func (mi mediaFullInfo) Disc(disc *md.Disc) []*md.Track {
+// Диск релиза с указанным номером. В отличие от md.Release.Disc, диски для
+// пропущенных номеров не создаются.
+func discByNumber(r *md.Release, number int) *md.Disc {
+	for _, disc := range r.Discs {
+		if disc.Number == number {
+			return disc
+		}
+	}
+	disc := md.NewDisc(number)
+	r.Discs = append(r.Discs, disc)
+	return disc
+}
+
+// Disc заполняет сведения о носителе и возвращает его треки.
+func (mi mediaFullInfo) Disc(disc *md.Disc, mp *mapping) []*md.Track {
+	disc.Title = mi.Title
+	disc.Format = discFormat(mi.Format)
 	var tracks []*md.Track
 	for _, tr := range mi.Tracks {
-		tracks = append(tracks, tr.Track(disc))
+		tracks = append(tracks, tr.Track(disc, mp))
 	}
 	return tracks
 }
 
-func (tr *track) Track(disc *md.Disc) *md.Track {
+// Соответствие фрагментов наименований форматов носителей Musicbrainz
+// (https://musicbrainz.org/doc/Release/Format) типам носителей. Порядок важен:
+// используется первое совпадение.
+var mediaFormats = []struct {
+	substr string
+	media  md.Media
+}{
+	{"SACD", md.MediaSACD},
+	{"VINYL", md.MediaLP},
+	{"FLEXI-DISC", md.MediaLP},
+	{"REEL-TO-REEL", md.MediaReeL},
+	{"DIGITAL MEDIA", md.MediaDigital},
+	{"DOWNLOAD CARD", md.MediaDigital},
+	{"CD", md.MediaCD},
+}
+
+// Формат носителя по наименованию формата Musicbrainz. Уточненное наименование
+// формата (например, `12" Vinyl` или "Hybrid SACD") сохраняется в атрибутах.
+func discFormat(format string) *md.DiscFormat {
+	df := &md.DiscFormat{}
+	upperFormat := strings.ToUpper(format)
+	for _, mf := range mediaFormats {
+		if strings.Contains(upperFormat, mf.substr) {
+			df.Media = mf.media
+			if upperFormat != mf.substr {
+				df.Attrs = append(df.Attrs, format)
+			}
+			return df
+		}
+	}
+	if format != "" {
+		df.Attrs = append(df.Attrs, format)
+	}
+	return df
+}
+
+// Credits возвращает указание исполнителей трека, а при его отсутствии -
+// исполнителей записи или, в последнюю очередь, релиза.
+func (tr *track) Credits(release artistCredits) artistCredits {
+	if len(tr.ArtistCredits) > 0 {
+		return tr.ArtistCredits
+	}
+	if len(tr.Recording.ArtistCredits) > 0 {
+		return tr.Recording.ArtistCredits
+	}
+	return release
+}
+
+func (tr *track) Track(disc *md.Disc, mp *mapping) *md.Track {
 	track := md.NewTrack()
 	if disc != nil {
 		track.LinkWithDisc(disc)
 	}
 	track.Position = strconv.Itoa(int(tr.Position))
+	if tr.Number != "" && tr.Number != track.Position {
+		track.Unprocessed[TrackNumberKey] = tr.Number
+	}
 	track.Title = tr.Title
+	// длительность трека в релизе может отличаться от длительности записи
 	track.Duration = intutils.Duration(tr.Length)
-	for _, rel := range tr.Recording.Relations {
-		rel.AddActor(track)
+	if tr.Length == 0 {
+		track.Duration = intutils.Duration(tr.Recording.Length)
+	}
+	if tr.ID != "" {
+		track.IDs[md.MusicbrainzReleaseTrackID.String()] = tr.ID
+	}
+	tr.Recording.addTo(track, mp)
+	return track
+}
+
+// Track возвращает трек вне релиза для записи, полученной при просмотре записей
+// артиста.
+func (rec recording) Track(mp *mapping) *md.Track {
+	track := md.NewTrack()
+	track.Title = rec.Title
+	track.Duration = intutils.Duration(rec.Length)
+	rec.addTo(track, mp)
+	if len(rec.ISRCs) > 0 {
+		track.Record.IDs[md.ISRC] = rec.ISRCs[0]
+	}
+	rec.ArtistCredits.AddTrackPerformers(track)
+	var artistGenres genres
+	for _, ac := range rec.ArtistCredits {
+		artistGenres = append(artistGenres, ac.Artist.Genres...)
 	}
+	track.Record.Genres = mp.genres.Select(rec.Genres, artistGenres)
+	setGenres(track.Unprocessed, RecordGenresKey, rec.Genres)
+	setGenres(track.Unprocessed, RecordTagsKey, rec.Tags)
+	setRating(track.Unprocessed, RecordRatingKey, rec.Rating, rec.UserRating)
+	setUserTags(track.Unprocessed, RecordUserTagsKey, rec.UserTags)
 	return track
 }
 
-func (rel *relation) AddActor(track *md.Track) {
+// Сведения записи трека: длительность, идентификатор, уточнение и связи.
+func (rec *recording) addTo(track *md.Track, mp *mapping) {
+	track.Record.Duration = rec.Length
+	if rec.ID != "" {
+		track.Record.IDs[md.MusicbrainzRecordingID] = rec.ID
+	}
+	if rec.Disambiguation != "" {
+		track.Unprocessed[RecordDisambiguationKey] = rec.Disambiguation
+	}
+	if rec.Video {
+		track.Unprocessed[VideoKey] = "true"
+	}
+	for _, rel := range rec.Relations {
+		if rel.TargetType == "work" {
+			rel.AddWork(track)
+			continue
+		}
+		rel.AddActor(track, mp.roles)
+	}
+}
+
+// AddToRelease размещает сведения связи релиза или группы релизов: роли
+// участников релиза и внешние ссылки. Идентификатор Discogs из ссылки
+// сохраняется под ключом discogsKey.
+func (rel *relation) AddToRelease(stub *md.ReleaseStub, discogsKey md.ReleaseID, roles RoleMapping) {
+	switch rel.TargetType {
+	case "artist":
+		if rel.Artist.Name == "" {
+			return
+		}
+		names, _ := roles.Roles(rel)
+		for _, role := range names {
+			stub.ActorRoles.Add(rel.Artist.Name, role)
+		}
+		addRoleCredit(stub.Unprocessed, ReleaseCreditsKey, rel.Credit(names))
+		if rel.Artist.ID != "" {
+			stub.Actors.Add(rel.Artist.Name, md.MusicbrainzArtistID, rel.Artist.ID)
+		}
+		addArtist(stub.Unprocessed, ReleaseArtistsKey, rel.Artist.Info(rel.TargetCredit))
+	case "url":
+		if rel.URL == nil || rel.URL.Resource == "" {
+			return
+		}
+		if rel.Type == "discogs" {
+			if id := discogsID(rel.URL.Resource); id != "" {
+				stub.IDs[discogsKey] = id
+			}
+		}
+		urls, _ := URLs(stub.Unprocessed)
+		if urls == nil {
+			urls = map[string][]string{}
+		}
+		if !collection.ContainsStr(rel.URL.Resource, urls[rel.Type]) {
+			urls[rel.Type] = append(urls[rel.Type], rel.URL.Resource)
+		}
+		setExtraJSON(stub.Unprocessed, URLsKey, urls)
+	case "release":
+		rel.addTranslation(stub)
+	}
+}
+
+// URLs возвращает внешние ссылки релиза по типам связей Musicbrainz
+// ("discogs", "bandcamp", "wikidata", "purchase for download"...).
+func URLs(extra collection.StrMap) (map[string][]string, error) {
+	var ret map[string][]string
+	err := extraJSON(extra, URLsKey, &ret)
+	return ret, err
+}
+
+// Идентификатор Discogs из ссылки вида "https://www.discogs.com/release/123"
+// или "https://www.discogs.com/master/123".
+func discogsID(u string) string {
+	parts := strings.Split(strings.TrimRight(u, "/"), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == "release" || parts[i] == "master" {
+			return strings.SplitN(parts[i+1], "-", 2)[0]
+		}
+	}
+	return ""
+}
+
+// AddActor размещает роли артиста связи записи в коллекциях трека согласно
+// таблице соответствия roles.
+func (rel *relation) AddActor(track *md.Track, roles RoleMapping) {
 	if rel.Artist.Name != "" {
-		var roles []string
-		if rel.Type == "instrument" {
-			roles = rel.Attributes
-		} else {
-			roles = []string{rel.Type}
+		names, target := roles.Roles(rel)
+		actors := target.Actors(track)
+		for _, role := range names {
+			actors.Add(rel.Artist.Name, role)
 		}
-		for _, role := range roles {
-			ActorsByRole(track, role).Add(rel.Artist.Name, role)
-			track.Actors.Add(rel.Artist.Name, md.DiscogsArtistID, rel.Artist.ID)
+		addRoleCredit(track.Unprocessed, CreditsKey, rel.Credit(names))
+		if rel.Artist.ID != "" {
+			track.Actors.Add(rel.Artist.Name, md.MusicbrainzArtistID, rel.Artist.ID)
 		}
+		addArtist(track.Unprocessed, ArtistsKey, rel.Artist.Info(rel.TargetCredit))
+	}
+}
+
+// AddWork заполняет сведения о произведении, исполнением которого является запись.
+func (rel *relation) AddWork(track *md.Track) {
+	if rel.Work == nil {
+		return
+	}
+	track.Composition.Title = rel.Work.Title
+	if rel.Work.ID != "" {
+		track.Composition.IDs[md.MusicbrainzWorkID.String()] = rel.Work.ID
+	}
+}
+
+// Credit возвращает подробности участия артиста связи в указанных ролях.
+func (rel *relation) Credit(roles []string) RoleCredit {
+	return RoleCredit{
+		ArtistID:           rel.Artist.ID,
+		Artist:             rel.Artist.Name,
+		CreditedAs:         rel.TargetCredit,
+		Type:               rel.Type,
+		Roles:              roles,
+		Begin:              rel.Begin,
+		End:                rel.End,
+		Ended:              rel.Ended,
+		Values:             nonEmpty(rel.AttributeValues),
+		CreditedAttributes: nonEmpty(rel.AttributeCredits),
+		AttributeIDs:       nonEmpty(rel.AttributeIDs),
 	}
 }
 
-// ActorsByRole определяет коллекцию для размещения описания по наименованию роли.
-// Это может быть коллекция для описания акторов произведения, записи или релиза.
-func ActorsByRole(track *md.Track, role string) *md.ActorRoles {
-	switch role {
-	case "design", "illustration", "design/illustration", "photography":
-		return &track.ActorRoles
-	// TODO: проверить!
-	case "composer", "lyricist", "writer":
-		return &track.Composition.ActorRoles
-	default:
-		return &track.Record.ActorRoles
+func nonEmpty(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
 	}
+	return m
 }
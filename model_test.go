@@ -0,0 +1,567 @@
+package musicbrainz
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+	intutils "github.com/ytsiuryn/go-intutils"
+)
+
+// Преобразование тестового релиза в общий формат.
+func testRelease(t *testing.T) *md.Release {
+	var ri releaseInfo
+	data, err := ioutil.ReadFile(testReleaseJSON)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &ri))
+	release := md.NewRelease()
+	ri.Release(release, newMapping())
+	return release
+}
+
+func TestDiscFormat(t *testing.T) {
+	for format, expected := range map[string]md.DiscFormat{
+		"CD":            {Media: md.MediaCD},
+		"Enhanced CD":   {Media: md.MediaCD, Attrs: []string{"Enhanced CD"}},
+		"Hybrid SACD":   {Media: md.MediaSACD, Attrs: []string{"Hybrid SACD"}},
+		`12" Vinyl`:     {Media: md.MediaLP, Attrs: []string{`12" Vinyl`}},
+		"Digital Media": {Media: md.MediaDigital},
+		"Cassette":      {Attrs: []string{"Cassette"}},
+		"":              {},
+	} {
+		assert.Equal(t, expected, *discFormat(format), format)
+	}
+}
+
+func TestMediaMapping(t *testing.T) {
+	release := testRelease(t)
+	require.Len(t, release.Discs, 1)
+	disc := release.Discs[0]
+	assert.Equal(t, 1, disc.Number)
+	assert.Equal(t, md.MediaLP, disc.Format.Media)
+	assert.Equal(t, []string{`12" Vinyl`}, disc.Format.Attrs)
+	assert.Equal(t, disc, release.Tracks[0].Disc())
+
+	ri := releaseInfo{Media: []mediaFullInfo{
+		{Position: 2, Format: "DVD-Video", Title: "Bonus", Tracks: []track{{Position: 1}}},
+		{Position: 1, Format: "CD", Tracks: []track{{Position: 1}, {Position: 2}}},
+	}}
+	release = md.NewRelease()
+	ri.Release(release, newMapping())
+	require.Len(t, release.Discs, 2)
+	assert.Equal(t, 1, release.Discs[0].Number)
+	assert.Equal(t, 2, release.Discs[1].Number)
+	assert.Equal(t, "Bonus", release.Discs[1].Title)
+	assert.Equal(t, md.MediaCD, release.Discs[0].Format.Media)
+	assert.Same(t, release.Discs[1], release.Tracks[0].Disc())
+	assert.Same(t, release.Discs[0], release.Tracks[1].Disc())
+	assert.Same(t, release.Discs[0], release.Tracks[2].Disc())
+	assert.Equal(t, 2, release.TotalDiscs)
+	assert.Equal(t, 3, release.TotalTracks)
+
+	// носители с пропуском номера
+	ri = releaseInfo{Media: []mediaFullInfo{
+		{Position: 3, Format: "CD", Tracks: []track{{Position: 1}}},
+		{Position: 1, Format: "CD", Tracks: []track{{Position: 1}}},
+	}}
+	release = md.NewRelease()
+	ri.Release(release, newMapping())
+	require.Len(t, release.Discs, 2)
+	assert.Equal(t, 2, release.TotalDiscs)
+	assert.Equal(t, 1, release.Discs[0].Number)
+	assert.Equal(t, 3, release.Discs[1].Number)
+	assert.Same(t, release.Discs[1], release.Tracks[0].Disc())
+	assert.Equal(t, 3, release.Tracks[0].Disc().Number)
+	assert.Same(t, release.Discs[0], release.Tracks[1].Disc())
+}
+
+func TestPublishingDetails(t *testing.T) {
+	release := testRelease(t)
+	assert.Equal(t, md.ReleaseStatusOfficial, release.ReleaseStatus)
+	assert.Equal(t, "Gatefold Cover", release.Unprocessed[PackagingKey])
+	events, err := ReleaseEvents(release)
+	require.NoError(t, err)
+	assert.Equal(t, []ReleaseEvent{{
+		Date:    "1973-03-24",
+		Country: "GB",
+		Area:    "United Kingdom",
+		AreaID:  "8a754a16-0027-3a29-b6d7-2b40ea0481ed",
+	}}, events)
+
+	release = md.NewRelease()
+	decodeReleaseStatus(release, "Pseudo-Release")
+	assert.Equal(t, md.ReleaseStatus(0), release.ReleaseStatus)
+	assert.Equal(t, "Pseudo-Release", release.Unprocessed[ReleaseStatusKey])
+}
+
+func TestPartialDates(t *testing.T) {
+	for s, expected := range map[string]Date{
+		"1973-03-24": {Year: 1973, Month: 3, Day: 24, Precision: PrecisionDay},
+		"1973-03":    {Year: 1973, Month: 3, Precision: PrecisionMonth},
+		"1973":       {Year: 1973, Precision: PrecisionYear},
+		"1973-13":    {Year: 1973, Precision: PrecisionYear},
+		"":           {},
+	} {
+		assert.Equal(t, expected, ParseDate(s), s)
+	}
+	for _, s := range []string{"1973-03-24", "1973-03", "1973", ""} {
+		assert.Equal(t, s, ParseDate(s).String())
+	}
+
+	release := testRelease(t)
+	assert.Equal(t, 1973, release.Year)
+	assert.Equal(t, "1973-03-24", ReleaseDate(release.ReleaseStub).String())
+	assert.Equal(t, 1973, release.Original.Year)
+	assert.Equal(t, PrecisionDay, ReleaseDate(release.Original).Precision)
+}
+
+func TestArtistIDs(t *testing.T) {
+	release := testRelease(t)
+	assert.Equal(t, md.ActorIDs{md.MusicbrainzArtistID: "83d91898-7763-47d7-b03b-b92132375c47"},
+		release.Actors["Pink Floyd"])
+	albumArtists, err := Artists(release.Unprocessed, AlbumArtistsKey)
+	require.NoError(t, err)
+	assert.Equal(t, []Artist{{
+		ID:       "83d91898-7763-47d7-b03b-b92132375c47",
+		Name:     "Pink Floyd",
+		SortName: "Pink Floyd",
+	}}, albumArtists)
+
+	track := release.Tracks[0]
+	assert.Equal(t, "9774cfd1-8862-42bd-919e-156c31f079b4",
+		track.Actors["Alan Parsons"][md.MusicbrainzArtistID])
+	artists, err := Artists(track.Unprocessed, ArtistsKey)
+	require.NoError(t, err)
+	assert.Contains(t, artists, Artist{
+		ID:             "9774cfd1-8862-42bd-919e-156c31f079b4",
+		Name:           "Alan Parsons",
+		SortName:       "Parsons, Alan",
+		Disambiguation: "of The Alan Parsons Project",
+	})
+
+	// идентификаторы Musicbrainz не должны попадать в пространство имен Discogs
+	for _, tr := range release.Tracks {
+		for name, ids := range tr.Actors {
+			assert.NotContains(t, ids, md.DiscogsArtistID, name)
+		}
+	}
+	for name, ids := range release.Actors {
+		assert.NotContains(t, ids, md.DiscogsArtistID, name)
+	}
+
+	ac := artistCredit{Name: "Ziggy Stardust", Artist: trackArtist{ID: "id", Name: "David Bowie"}}
+	release = md.NewRelease()
+	ac.AddPerformer(release)
+	assert.Contains(t, release.ActorRoles, "David Bowie")
+	albumArtists, err = Artists(release.Unprocessed, AlbumArtistsKey)
+	require.NoError(t, err)
+	assert.Equal(t, "Ziggy Stardust", albumArtists[0].CreditedAs)
+}
+
+func TestArtistCreditJoinPhrases(t *testing.T) {
+	acs := artistCredits{
+		{Name: "Santana", JoinPhrase: " feat. ", Artist: trackArtist{ID: "1", Name: "Santana"}},
+		{Name: "Rob Thomas", Artist: trackArtist{ID: "2", Name: "Rob Thomas"}},
+	}
+	assert.Equal(t, "Santana feat. Rob Thomas", acs.String())
+
+	release := md.NewRelease()
+	acs.AddPerformers(release)
+	assert.Equal(t, "Santana feat. Rob Thomas", release.Unprocessed[AlbumArtistCreditKey])
+	assert.Len(t, release.ActorRoles.Filter(md.IsPerformer), 2)
+	assert.Equal(t, "2", release.Actors["Rob Thomas"][md.MusicbrainzArtistID])
+
+	release = testRelease(t)
+	assert.Equal(t, "Pink Floyd", release.Unprocessed[AlbumArtistCreditKey])
+	assert.Equal(t, "Pink Floyd", release.Tracks[0].Unprocessed[ArtistCreditKey])
+}
+
+func TestTrackArtistCredits(t *testing.T) {
+	various := artistCredits{{Name: "Various Artists", Artist: trackArtist{ID: "va", Name: "Various Artists"}}}
+	ri := releaseInfo{
+		ArtistCredit: various,
+		Media: []mediaFullInfo{{Position: 1, Tracks: []track{
+			{Position: 1, ArtistCredits: artistCredits{{Name: "Nico", Artist: trackArtist{ID: "1", Name: "Nico"}}}},
+			{Position: 2, Recording: recording{ArtistCredits: artistCredits{{Name: "Lou Reed", Artist: trackArtist{ID: "2", Name: "Lou Reed"}}}}},
+			{Position: 3},
+		}}}}
+	release := md.NewRelease()
+	ri.Release(release, newMapping())
+	require.Len(t, release.Tracks, 3)
+
+	assert.Equal(t, "Nico", release.Tracks[0].Unprocessed[ArtistCreditKey])
+	assert.Contains(t, release.Tracks[0].Record.Performers(), "Nico")
+	assert.NotContains(t, release.Tracks[0].Record.Performers(), "Various Artists")
+	assert.Equal(t, "1", release.Tracks[0].Actors["Nico"][md.MusicbrainzArtistID])
+
+	assert.Contains(t, release.Tracks[1].Record.Performers(), "Lou Reed")
+	assert.Contains(t, release.Tracks[2].Record.Performers(), "Various Artists")
+	assert.Equal(t, "Various Artists", release.Unprocessed[AlbumArtistCreditKey])
+}
+
+func TestReleaseRelations(t *testing.T) {
+	release := testRelease(t)
+	assert.Contains(t, release.ActorRoles["Hipgnosis"], "photography")
+	assert.Contains(t, release.ActorRoles["George Hardie"], "design/illustration")
+	artists, err := Artists(release.Unprocessed, ReleaseArtistsKey)
+	require.NoError(t, err)
+	assert.Len(t, artists, 2)
+
+	ri := releaseInfo{
+		Relations: []relation{
+			{TargetType: "artist", Type: "producer", Artist: trackArtist{ID: "1", Name: "Pink Floyd"}},
+			{TargetType: "url", Type: "discogs", URL: &urlTarget{Resource: "https://www.discogs.com/release/1873013"}},
+			{TargetType: "url", Type: "purchase for download", URL: &urlTarget{Resource: "https://pinkfloyd.bandcamp.com/"}},
+		},
+		ReleaseGroup: releaseGroup{Relations: []relation{
+			{TargetType: "url", Type: "discogs", URL: &urlTarget{Resource: "https://www.discogs.com/master/10362-Pink-Floyd-The-Dark-Side-Of-The-Moon"}},
+			{TargetType: "url", Type: "wikidata", URL: &urlTarget{Resource: "https://www.wikidata.org/wiki/Q150901"}},
+		}},
+	}
+	release = md.NewRelease()
+	ri.Release(release, newMapping())
+	assert.Equal(t, []string{"producer"}, release.ActorRoles["Pink Floyd"])
+	assert.Equal(t, "1873013", release.IDs[md.DiscogsReleaseID])
+	assert.Equal(t, "10362", release.Original.IDs[md.DiscogsMasterID])
+	urls, err := URLs(release.Unprocessed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://pinkfloyd.bandcamp.com/"}, urls["purchase for download"])
+	urls, err = URLs(release.Original.Unprocessed)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://www.wikidata.org/wiki/Q150901"}, urls["wikidata"])
+}
+
+func TestRoleMapping(t *testing.T) {
+	for _, tc := range []struct {
+		rel    relation
+		roles  []string
+		target RoleTarget
+	}{
+		{relation{Type: "vocal", Attributes: []string{"lead vocals"}}, []string{"lead vocals"}, TargetRecord},
+		{relation{Type: "vocal"}, []string{"vocals"}, TargetRecord},
+		{relation{Type: "instrument", Attributes: []string{"guest", "bass guitar", "piano"}},
+			[]string{"guest bass guitar", "guest piano"}, TargetRecord},
+		{relation{Type: "engineer", Attributes: []string{"assistant"}}, []string{"assistant engineer"}, TargetRecord},
+		{relation{Type: "producer", Attributes: []string{"executive"}}, []string{"executive producer"}, TargetRecord},
+		{relation{Type: "performing orchestra"}, []string{"orchestra"}, TargetRecord},
+		{relation{Type: "mix"}, []string{"mixing engineer"}, TargetRecord},
+		{relation{Type: "composer"}, []string{"composer"}, TargetComposition},
+		{relation{Type: "photography"}, []string{"photography"}, TargetTrack},
+		{relation{Type: "unknown"}, []string{"unknown"}, TargetRecord},
+	} {
+		roles, target := DefaultRoles.Roles(&tc.rel)
+		assert.Equal(t, tc.roles, roles, tc.rel.Type)
+		assert.Equal(t, tc.target, target, tc.rel.Type)
+	}
+
+	var custom RoleMapping
+	require.NoError(t, json.Unmarshal([]byte(`{"mix": {"name": "mixer", "target": "track"}}`), &custom))
+	m := New("test", "", "", WithFetcher(FetcherFunc(nil)), WithRoles(custom))
+	track := md.NewTrack()
+	rel := relation{Type: "mix", Artist: trackArtist{ID: "1", Name: "Alan Parsons"}}
+	rel.AddActor(track, m.mapping(nil).roles)
+	assert.Equal(t, []string{"mixer"}, track.ActorRoles["Alan Parsons"])
+	assert.Contains(t, m.roles, "conductor")
+	assert.Equal(t, "mixing engineer", DefaultRoles["mix"].Name)
+}
+
+func TestRoleCredits(t *testing.T) {
+	data := []byte(`{
+		"type": "instrument", "target-type": "artist", "target-credit": "Dave Gilmour",
+		"begin": "1972-06", "end": "1973-01", "ended": true,
+		"attributes": ["guest", "bass guitar"],
+		"attribute-credits": {"bass guitar": "bass"},
+		"attribute-ids": {"bass guitar": "17f9f065-2312-4a24-8309-6f6dd63e2e33"},
+		"artist": {"id": "1", "name": "David Gilmour"}}`)
+	var rel relation
+	require.NoError(t, json.Unmarshal(data, &rel))
+	track := md.NewTrack()
+	rel.AddActor(track, DefaultRoles)
+	rel.AddActor(track, DefaultRoles)
+	credits, err := RoleCredits(track.Unprocessed, CreditsKey)
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	assert.Equal(t, RoleCredit{
+		ArtistID:           "1",
+		Artist:             "David Gilmour",
+		CreditedAs:         "Dave Gilmour",
+		Type:               "instrument",
+		Roles:              []string{"guest bass guitar"},
+		Begin:              "1972-06",
+		End:                "1973-01",
+		Ended:              true,
+		CreditedAttributes: map[string]string{"bass guitar": "bass"},
+		AttributeIDs:       map[string]string{"bass guitar": "17f9f065-2312-4a24-8309-6f6dd63e2e33"},
+	}, credits[0])
+
+	stub := md.NewRelease().ReleaseStub
+	rel = relation{Type: "engineer", TargetType: "artist", Attributes: []string{"task"},
+		AttributeValues: map[string]string{"task": "tape operator"},
+		Artist:          trackArtist{Name: "Peter James"}}
+	rel.AddToRelease(stub, md.DiscogsReleaseID, DefaultRoles)
+	credits, err = RoleCredits(stub.Unprocessed, ReleaseCreditsKey)
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	assert.Equal(t, map[string]string{"task": "tape operator"}, credits[0].Values)
+	assert.Equal(t, []string{"engineer"}, credits[0].Roles)
+}
+
+func TestReleaseGroupTypes(t *testing.T) {
+	release := testRelease(t)
+	assert.Equal(t, md.ReleaseTypeAlbum, release.ReleaseType)
+	primary, secondary := ReleaseGroupTypes(release)
+	assert.Equal(t, "Album", primary)
+	assert.Empty(t, secondary)
+
+	release = md.NewRelease()
+	rgi := releaseGroup{PrimaryType: "EP", SecondaryTypes: []string{"Compilation", "Live", "Soundtrack"}}
+	rgi.ReleaseGroup(release, newMapping())
+	assert.Equal(t, md.ReleaseTypeMiniAlbum, release.ReleaseType)
+	assert.Equal(t, md.ReleaseRepeatCompilation, release.ReleaseRepeat)
+	assert.Equal(t, md.ReleaseOriginLive, release.ReleaseOrigin)
+	_, secondary = ReleaseGroupTypes(release)
+	assert.Equal(t, []string{"Compilation", "Live", "Soundtrack"}, secondary)
+
+	f := &ReleaseTypeFilter{Primary: []string{"album", "ep"}, Exclude: []string{"live"}, Prefer: []string{"soundtrack"}}
+	assert.Equal(t, []string{`(primarytype:"album" OR primarytype:"ep")`, `NOT secondarytype:"live"`}, f.query())
+	assert.False(t, f.Accept(release))
+	assert.True(t, f.Accept(md.NewRelease()))
+	assert.InDelta(t, 0.55, f.Boost(release, 0.5), 1e-9)
+	assert.Equal(t, 0.5, f.Boost(testRelease(t), 0.5))
+	var nilFilter *ReleaseTypeFilter
+	assert.True(t, nilFilter.Accept(release))
+	assert.Empty(t, nilFilter.query())
+}
+
+func TestGenres(t *testing.T) {
+	release := testRelease(t)
+	assert.Equal(t, []string{"progressive rock", "rock", "psychedelic rock", "experimental"},
+		release.Tracks[0].Record.Genres)
+	rgGenres, err := Genres(release.Original.Unprocessed, GenresKey)
+	require.NoError(t, err)
+	assert.Equal(t, Genre{Name: "progressive rock", Count: 23}, rgGenres[0])
+	recordGenres, err := Genres(release.Tracks[0].Unprocessed, RecordGenresKey)
+	require.NoError(t, err)
+	assert.Len(t, recordGenres, 4)
+	artistGenres, err := ArtistGenres(release)
+	require.NoError(t, err)
+	assert.NotEmpty(t, artistGenres["Pink Floyd"])
+
+	var ri releaseInfo
+	data, err := ioutil.ReadFile(testReleaseJSON)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &ri))
+	ri.Media[0].Tracks[1].Recording.Genres = nil
+	release = md.NewRelease()
+	ri.Release(release, &mapping{roles: DefaultRoles, genres: GenrePolicy{MinVotes: 2, MaxCount: 2, Inherit: true}})
+	assert.Equal(t, []string{"progressive rock", "rock"}, release.Tracks[0].Record.Genres)
+	assert.Equal(t, []string{"progressive rock", "rock"}, release.Tracks[1].Record.Genres)
+
+	policy := GenrePolicy{MinVotes: 5}
+	assert.Nil(t, policy.Select(genres{{Name: "rock", Count: 1}}, genres{{Name: "pop", Count: 10}}))
+	policy.Inherit = true
+	assert.Equal(t, []string{"pop"}, policy.Select(genres{{Name: "rock", Count: 1}}, genres{{Name: "pop", Count: 10}}))
+}
+
+func TestRatings(t *testing.T) {
+	data := []byte(`{
+		"id": "1", "title": "Album",
+		"user-tags": [{"name": "favourite"}],
+		"artist-credit": [{"name": "Band", "artist": {"id": "2", "name": "Band",
+			"rating": {"value": 4.5, "votes-count": 12}}}],
+		"release-group": {"id": "3", "rating": {"value": 4.25, "votes-count": 8},
+			"user-rating": {"value": 5}, "user-tags": [{"name": "classic"}]},
+		"media": [{"position": 1, "tracks": [{"position": 1, "recording": {"id": "4",
+			"rating": {"value": null, "votes-count": 0}, "user-rating": {"value": 3}}}]}]}`)
+	var ri releaseInfo
+	require.NoError(t, json.Unmarshal(data, &ri))
+	release := md.NewRelease()
+	ri.Release(release, newMapping())
+
+	rt, err := RatingOf(release.Original.Unprocessed, RatingKey)
+	require.NoError(t, err)
+	assert.Equal(t, &Rating{Value: 4.25, Votes: 8, UserValue: 5}, rt)
+	rt, err = RatingOf(release.Tracks[0].Unprocessed, RecordRatingKey)
+	require.NoError(t, err)
+	assert.Equal(t, &Rating{UserValue: 3}, rt)
+	ratings, err := ArtistRatings(release)
+	require.NoError(t, err)
+	assert.Equal(t, Rating{Value: 4.5, Votes: 12}, ratings["Band"])
+	tags, err := UserTags(release.Original.Unprocessed, UserTagsKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"classic"}, tags)
+	tags, err = UserTags(release.Unprocessed, UserTagsKey)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"favourite"}, tags)
+
+	rt, err = RatingOf(md.NewRelease().Unprocessed, RatingKey)
+	assert.NoError(t, err)
+	assert.Nil(t, rt)
+}
+
+func TestAliases(t *testing.T) {
+	as := aliases{
+		{Name: "Пинк Флойд", Locale: "ru", Type: searchHintAlias, Primary: true},
+		{Name: "Пинк Флоид", Locale: "ru_RU"},
+		{Name: "Пинк Флойд", Locale: "ru", Primary: true, Type: "Artist name"},
+		{Name: "ピンク・フロイド", Locale: "ja", Primary: true},
+	}
+	assert.Equal(t, "Пинк Флойд", as.Name("ru"))
+	assert.Equal(t, "Пинк Флойд", as.Name("ru_RU"))
+	assert.Equal(t, "Пинк Флоид", aliases{as[1]}.Name("ru-RU"))
+	assert.Equal(t, "", as.Name("en"))
+	assert.Equal(t, "", as.Name(""))
+	for locale, lang := range map[string]string{
+		"ru": "ru", "ru_RU": "ru", "ru-RU": "ru", "": "", "-": "", "_": "", "--": "", "_RU": "RU",
+	} {
+		assert.Equal(t, lang, language(locale), locale)
+		assert.NotPanics(t, func() { as.Name(locale) }, locale)
+	}
+	assert.Equal(t, "", aliases{{Name: "x", Locale: "-"}}.Name("_"))
+
+	data := []byte(`{
+		"id": "1", "title": "The Dark Side of the Moon",
+		"artist-credit": [{"name": "Pink Floyd", "artist": {"id": "2", "name": "Pink Floyd",
+			"sort-name": "Pink Floyd", "aliases": [{"name": "Пинк Флойд", "locale": "ru", "primary": true}]}}],
+		"label-info": [{"catalog-number": "SHVL 804", "label": {"id": "3", "name": "Harvest",
+			"aliases": [{"name": "Харвест", "locale": "ru", "primary": true}]}}],
+		"release-group": {"id": "4", "title": "The Dark Side of the Moon",
+			"aliases": [{"name": "Обратная сторона Луны", "locale": "ru", "primary": true}]},
+		"media": [{"position": 1, "tracks": [{"position": 1, "title": "Money", "recording": {"id": "5",
+			"relations": [{"type": "performance", "target-type": "work", "work": {"id": "6", "title": "Money",
+				"aliases": [{"name": "Деньги", "locale": "ru", "primary": true}]}}]}}]}]}`)
+	var ri releaseInfo
+	require.NoError(t, json.Unmarshal(data, &ri))
+	release := md.NewRelease()
+	ri.Release(release, &mapping{roles: DefaultRoles, locale: "ru"})
+
+	assert.Contains(t, release.ActorRoles, "Пинк Флойд")
+	assert.Equal(t, "2", release.Actors["Пинк Флойд"][md.MusicbrainzArtistID])
+	artists, err := Artists(release.Unprocessed, AlbumArtistsKey)
+	require.NoError(t, err)
+	assert.Equal(t, "Pink Floyd", artists[0].SortName)
+	assert.Empty(t, artists[0].CreditedAs)
+	assert.Equal(t, "Пинк Флойд", release.Unprocessed[AlbumArtistCreditKey])
+	assert.Equal(t, "Харвест", release.Publishing.Labels[0].Label)
+	assert.Equal(t, "Обратная сторона Луны", release.Original.Title)
+	assert.Equal(t, "Деньги", release.Tracks[0].Composition.Title)
+	assert.Equal(t, "6", release.Tracks[0].Composition.IDs[md.MusicbrainzWorkID.String()])
+	originals, err := OriginalNames(release)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Пинк Флойд":            "Pink Floyd",
+		"Харвест":               "Harvest",
+		"Обратная сторона Луны": "The Dark Side of the Moon",
+		"Деньги":                "Money",
+	}, originals)
+
+	// имя в указании, отличное от имени артиста, сохраняется в CreditedAs
+	acs := artistCredits{
+		{Name: "The Pink Floyd", JoinPhrase: " & ", Artist: trackArtist{Name: "Pink Floyd", Aliases: as}},
+		{Name: "Pink Floyd", Artist: trackArtist{Name: "Pink Floyd", Aliases: as}},
+	}
+	(&localizer{locale: "ru", originals: map[string]string{}}).artistCredits(acs)
+	assert.Equal(t, "The Pink Floyd", acs[0].Artist.Info(acs[0].Name).CreditedAs)
+	assert.Empty(t, acs[1].Artist.Info(acs[1].Name).CreditedAs)
+	assert.Equal(t, "The Pink Floyd & Пинк Флойд", acs.String())
+}
+
+func TestTrackDetails(t *testing.T) {
+	release := testRelease(t)
+	tr := release.Tracks[2]
+	assert.Equal(t, "3", tr.Position)
+	assert.Equal(t, "A3", tr.Unprocessed[TrackNumberKey])
+	assert.Equal(t, intutils.Duration(230600), tr.Duration)
+	assert.Equal(t, int32(226000), tr.Record.Duration)
+	assert.Equal(t, "ffb7f6b2-b20d-3cb4-bc1b-5b6f4c3c4054", tr.IDs[md.MusicbrainzReleaseTrackID.String()])
+	assert.Equal(t, "747a79a7-644e-42d4-be86-9adaf44393d8", tr.Record.IDs[md.MusicbrainzRecordingID])
+	assert.Equal(t, "original studio stereo mix", tr.Unprocessed[RecordDisambiguationKey])
+	assert.NotContains(t, tr.Unprocessed, VideoKey)
+
+	tr = (&track{Position: 1, Number: "1", Recording: recording{Length: 1000, Video: true}}).Track(nil, newMapping())
+	assert.Equal(t, "1", tr.Position)
+	assert.NotContains(t, tr.Unprocessed, TrackNumberKey)
+	assert.Equal(t, intutils.Duration(1000), tr.Duration)
+	assert.Equal(t, "true", tr.Unprocessed[VideoKey])
+}
+
+func TestLabels(t *testing.T) {
+	release := testRelease(t)
+	require.Len(t, release.Publishing.Labels, 1)
+	assert.Equal(t, "993af7f6-bb99-456b-83e7-5e728ea80a0e", release.Publishing.Labels[0].IDs[md.MusicbrainzLabelID])
+	assert.Equal(t, `{"Harvest":"LC-01305"}`, release.Unprocessed[LabelCodesKey])
+
+	warp := label{ID: "1", Name: "Warp", LabelCode: 2070}
+	ri := releaseInfo{LabelInfo: []labelInfo{
+		{Label: warp},
+		{Label: warp, CatalogNumber: "WARPCD92"},
+		{Label: warp, CatalogNumber: "WARPLP92"},
+		{Label: warp, CatalogNumber: "WARPCD92"},
+		{Label: label{ID: "157afde4-4bf5-4039-8ad2-5a15acc85176", Name: "[no label]"}, CatalogNumber: "[none]"},
+		{Label: label{ID: "2", Name: "Bleep"}, CatalogNumber: "[none]"},
+	}}
+	release = md.NewRelease()
+	ri.Release(release, newMapping())
+	require.Len(t, release.Publishing.Labels, 2)
+	assert.Equal(t, "Warp", release.Publishing.Labels[0].Label)
+	assert.Equal(t, "WARPCD92", release.Publishing.Labels[0].Catno)
+	assert.Equal(t, "1", release.Publishing.Labels[0].IDs[md.MusicbrainzLabelID])
+	assert.Equal(t, "Bleep", release.Publishing.Labels[1].Label)
+	assert.Empty(t, release.Publishing.Labels[1].Catno)
+	assert.Equal(t, "2", release.Publishing.Labels[1].IDs[md.MusicbrainzLabelID])
+	catnos, err := CatalogNumbers(release)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"Warp": {"WARPCD92", "WARPLP92"}}, catnos)
+	codes, err := LabelCodes(release)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Warp": "LC-02070"}, codes)
+}
+
+func TestRenderAnnotation(t *testing.T) {
+	annotation := "== Credits ==\n" +
+		"'''Remastered''' by ''James Guthrie'' at [http://example.com|Das Boot].\n" +
+		"* [artist:83d91898-7763-47d7-b03b-b92132375c47|Pink Floyd]\n" +
+		"** see [http://pinkfloyd.com]\n" +
+		"# first\n" +
+		"# second\n" +
+		"----\n" +
+		"[none] left as is"
+
+	assert.Equal(t, "Credits\n"+
+		"Remastered by James Guthrie at Das Boot (http://example.com).\n"+
+		"- Pink Floyd\n"+
+		"  - see http://pinkfloyd.com\n"+
+		"1. first\n"+
+		"2. second\n"+
+		"\n"+
+		"[none] left as is", RenderAnnotation(annotation, NotesText))
+
+	assert.Equal(t, "## Credits\n"+
+		"**Remastered** by *James Guthrie* at [Das Boot](http://example.com).\n"+
+		"- [Pink Floyd](https://musicbrainz.org/artist/83d91898-7763-47d7-b03b-b92132375c47)\n"+
+		"  - see <http://pinkfloyd.com>\n"+
+		"1. first\n"+
+		"2. second\n"+
+		"---\n"+
+		"[none] left as is", RenderAnnotation(annotation, NotesMarkdown))
+
+	assert.Equal(t, annotation, RenderAnnotation(annotation, NotesRaw))
+
+	for markup, expected := range map[string][2]string{
+		"'''''Animals''''' tour":      {"Animals tour", "***Animals*** tour"},
+		"empty '''''' and '''' marks": {"empty  and  marks", "empty  and  marks"},
+	} {
+		assert.Equal(t, expected[0], RenderAnnotation(markup, NotesText), markup)
+		assert.Equal(t, expected[1], RenderAnnotation(markup, NotesMarkdown), markup)
+	}
+
+	ri := releaseInfo{Annotation: "'''Limited''' edition", ReleaseGroup: releaseGroup{Annotation: "''Classic''"}}
+	release := md.NewRelease()
+	ri.Release(release, newMapping())
+	assert.Equal(t, "Limited edition", release.Notes)
+	assert.Equal(t, "Classic", release.Original.Notes)
+}
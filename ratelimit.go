@@ -0,0 +1,165 @@
+// Адаптивное ограничение частоты запросов к серверу Musicbrainz
+// (https://musicbrainz.org/doc/MusicBrainz_API/Rate_Limiting).
+
+package musicbrainz
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Максимальный интервал опроса, до которого может быть замедлен клиент.
+const maxPollingInterval = time.Minute
+
+// RetryPolicy описывает правила повтора запросов, отклоненных сервером из-за
+// превышения частоты запросов или временной недоступности.
+type RetryPolicy struct {
+	MaxRetries int           // количество повторов после первой попытки
+	BaseDelay  time.Duration // начальная задержка экспоненциального ожидания
+	MaxDelay   time.Duration // максимальная задержка между попытками (в т.ч. по Retry-After)
+}
+
+// DefaultRetryPolicy используется, если правила повтора не заданы явно.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// HTTPError описывает неуспешный ответ сервера, не подлежащий повтору.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// RateLimitError возвращается, если сервер продолжает отклонять запрос
+// после исчерпания всех попыток повтора или запрашивает ожидание (RetryAfter),
+// превышающее RetryPolicy.MaxDelay.
+type RateLimitError struct {
+	URL        string
+	StatusCode int
+	Attempts   int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %d %s after %d attempts (retry after %s)",
+		e.URL, e.StatusCode, http.StatusText(e.StatusCode), e.Attempts, e.RetryAfter)
+}
+
+// Определяет, следует ли повторить запрос с указанным кодом ответа.
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable,
+		http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Экспоненциальная задержка перед очередной попыткой со случайной составляющей.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Время ожидания, запрошенное сервером в заголовках Retry-After или X-RateLimit-*.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			if t := time.Unix(reset, 0); t.After(now) {
+				return t.Sub(now)
+			}
+		}
+	}
+	return 0
+}
+
+// throttle динамически изменяет интервал опроса пула запросов.
+// Для реализаций Fetcher, отличных от PollerFetcher, интервал только учитывается.
+type throttle struct {
+	mu       sync.Mutex
+	base     time.Duration
+	interval time.Duration
+	pf       *PollerFetcher
+}
+
+func newThrottle(base time.Duration, f Fetcher) *throttle {
+	pf, _ := f.(*PollerFetcher)
+	return &throttle{base: base, interval: base, pf: pf}
+}
+
+// Interval возвращает текущий интервал опроса.
+func (t *throttle) Interval() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interval
+}
+
+// Замедление опроса после отказа сервера.
+func (t *throttle) slowDown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	interval := t.interval * 2
+	if interval > maxPollingInterval {
+		interval = maxPollingInterval
+	}
+	t.set(interval)
+}
+
+// Подстройка интервала опроса по заголовкам X-RateLimit-* успешного ответа.
+// При отсутствии заголовков интервал постепенно возвращается к исходному.
+func (t *throttle) adapt(h http.Header, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	interval := t.interval - (t.interval-t.base)/4
+	remaining, err1 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, err2 := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 == nil && err2 == nil && remaining > 0 {
+		if window := time.Unix(reset, 0).Sub(now); window > 0 {
+			interval = window / time.Duration(remaining)
+			if t.pf != nil {
+				interval *= time.Duration(t.pf.Concurrency())
+			}
+		}
+	}
+	if interval < t.base {
+		interval = t.base
+	}
+	if interval > maxPollingInterval {
+		interval = maxPollingInterval
+	}
+	t.set(interval)
+}
+
+func (t *throttle) set(interval time.Duration) {
+	if interval == t.interval {
+		return
+	}
+	t.interval = interval
+	if t.pf != nil {
+		t.pf.SetPollingInterval(interval)
+	}
+}
@@ -0,0 +1,85 @@
+package musicbrainz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startThrottledServer(t *testing.T, failures int32) *httptest.Server {
+	return startRetryAfterServer(t, failures, "0", new(int32))
+}
+
+// Сервер, отклоняющий первые failures запросов с указанным заголовком Retry-After
+// и подсчитывающий количество запросов в calls.
+func startRetryAfterServer(t *testing.T, failures int32, retryAfter string, calls *int32) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"title": "ok"}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestRetryOnServiceUnavailable(t *testing.T) {
+	ts := startThrottledServer(t, 2)
+	m := newTestClient(t, WithBaseURL(ts.URL))
+
+	var out struct {
+		Title string `json:"title"`
+	}
+	require.NoError(t, m.decodeJSON(ts.URL, &out))
+	assert.Equal(t, "ok", out.Title)
+}
+
+func TestRateLimitErrorAfterRetries(t *testing.T) {
+	ts := startThrottledServer(t, 100)
+	m := newTestClient(t, WithBaseURL(ts.URL))
+
+	var rle *RateLimitError
+	require.True(t, errors.As(m.decodeJSON(ts.URL, &struct{}{}), &rle))
+	assert.Equal(t, http.StatusServiceUnavailable, rle.StatusCode)
+	assert.Equal(t, 3, rle.Attempts)
+	assert.True(t, m.throttle.Interval() > time.Millisecond)
+}
+
+func TestRetryAfterOverMaxDelay(t *testing.T) {
+	var calls int32
+	ts := startRetryAfterServer(t, 1, "3600", &calls)
+	m := newTestClient(t, WithBaseURL(ts.URL))
+
+	start := time.Now()
+	var rle *RateLimitError
+	require.True(t, errors.As(m.decodeJSON(ts.URL, &struct{}{}), &rle))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Equal(t, time.Hour, rle.RetryAfter)
+	assert.Equal(t, 1, rle.Attempts)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryAfterHeaders(t *testing.T) {
+	now := time.Unix(1000, 0)
+	h := http.Header{}
+	h.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, retryAfter(h, now))
+
+	h = http.Header{}
+	h.Set("X-RateLimit-Remaining", "0")
+	h.Set("X-RateLimit-Reset", "1010")
+	assert.Equal(t, 10*time.Second, retryAfter(h, now))
+
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		assert.True(t, p.backoff(attempt) <= p.MaxDelay)
+	}
+}
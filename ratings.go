@@ -0,0 +1,110 @@
+// Оценки и пользовательские теги Musicbrainz (https://musicbrainz.org/doc/Rating_System).
+
+package musicbrainz
+
+import (
+	md "github.com/ytsiuryn/ds-audiomd"
+	collection "github.com/ytsiuryn/go-collection"
+)
+
+// Ключи дополнительных сведений об оценках и тегах пользователя. Ключ RatingKey
+// используется для группы релизов (md.Release.Original.Unprocessed), а UserTagsKey -
+// для релиза и группы релизов.
+const (
+	RatingKey         = "rating"           // JSON Rating
+	UserTagsKey       = "user_tags"        // JSON-список тегов пользователя
+	ArtistRatingsKey  = "artist_ratings"   // JSON-словарь Rating по имени артиста
+	RecordRatingKey   = "record_rating"    // JSON Rating записи (md.Track.Unprocessed)
+	RecordUserTagsKey = "record_user_tags" // JSON-список тегов пользователя для записи
+)
+
+// Rating описывает оценку сообщества (среднее значение по шкале 0..5 и количество
+// голосов) и оценку пользователя, от имени которого выполнен запрос.
+type Rating struct {
+	Value     float64 `json:"value"`
+	Votes     int32   `json:"votes"`
+	UserValue float64 `json:"user_value,omitempty"`
+}
+
+type rating struct {
+	Value      float64 `json:"value"`
+	VotesCount int32   `json:"votes-count"`
+}
+
+type userTag struct {
+	Name string `json:"name"`
+}
+
+type userTags []userTag
+
+// Оценка сущности или nil при отсутствии оценок.
+func newRating(community, user *rating) *Rating {
+	ret := Rating{}
+	if community != nil {
+		ret.Value = community.Value
+		ret.Votes = community.VotesCount
+	}
+	if user != nil {
+		ret.UserValue = user.Value
+	}
+	if ret == (Rating{}) {
+		return nil
+	}
+	return &ret
+}
+
+// RatingOf возвращает оценку из дополнительных сведений по ключу RatingKey или
+// RecordRatingKey (nil при отсутствии оценок).
+func RatingOf(extra collection.StrMap, key string) (*Rating, error) {
+	if _, ok := extra[key]; !ok {
+		return nil, nil
+	}
+	var ret Rating
+	if err := extraJSON(extra, key, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// ArtistRatings возвращает оценки артистов релиза по их именам.
+func ArtistRatings(r *md.Release) (map[string]Rating, error) {
+	var ret map[string]Rating
+	err := extraJSON(r.Unprocessed, ArtistRatingsKey, &ret)
+	return ret, err
+}
+
+// UserTags возвращает теги пользователя из дополнительных сведений по ключу.
+func UserTags(extra collection.StrMap, key string) ([]string, error) {
+	var ret []string
+	err := extraJSON(extra, key, &ret)
+	return ret, err
+}
+
+func setRating(extra collection.StrMap, key string, community, user *rating) {
+	if rt := newRating(community, user); rt != nil {
+		setExtraJSON(extra, key, rt)
+	}
+}
+
+func setUserTags(extra collection.StrMap, key string, tags userTags) {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if len(names) > 0 {
+		setExtraJSON(extra, key, names)
+	}
+}
+
+func addArtistRating(r *md.Release, ta trackArtist) {
+	rt := newRating(ta.Rating, ta.UserRating)
+	if rt == nil {
+		return
+	}
+	ratings, _ := ArtistRatings(r)
+	if ratings == nil {
+		ratings = map[string]Rating{}
+	}
+	ratings[ta.Name] = *rt
+	setExtraJSON(r.Unprocessed, ArtistRatingsKey, ratings)
+}
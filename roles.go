@@ -0,0 +1,207 @@
+// Соответствие типов связей Musicbrainz (https://musicbrainz.org/relationships)
+// ролям акторов.
+
+package musicbrainz
+
+import (
+	"fmt"
+	"strings"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+// RoleTarget определяет коллекцию, в которую помещается роль актора трека.
+type RoleTarget int8
+
+// Коллекции ролей акторов трека.
+const (
+	TargetRecord      RoleTarget = iota // участники записи (md.Record.ActorRoles)
+	TargetComposition                   // авторы произведения (md.Work.ActorRoles)
+	TargetTrack                         // оформление и прочие участники (md.Track.ActorRoles)
+)
+
+var roleTargets = map[RoleTarget]string{
+	TargetRecord:      "record",
+	TargetComposition: "composition",
+	TargetTrack:       "track",
+}
+
+func (t RoleTarget) String() string {
+	return roleTargets[t]
+}
+
+// MarshalText представляет коллекцию ролей в конфигурации в виде строки.
+func (t RoleTarget) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText разбирает наименование коллекции ролей из конфигурации.
+func (t *RoleTarget) UnmarshalText(text []byte) error {
+	for k, v := range roleTargets {
+		if v == string(text) {
+			*t = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown role target: %s", text)
+}
+
+// Actors возвращает коллекцию ролей трека.
+func (t RoleTarget) Actors(track *md.Track) *md.ActorRoles {
+	switch t {
+	case TargetComposition:
+		return &track.Composition.ActorRoles
+	case TargetTrack:
+		return &track.ActorRoles
+	}
+	return &track.Record.ActorRoles
+}
+
+// Role описывает правило преобразования связи Musicbrainz в роль актора.
+type Role struct {
+	// Наименование роли. Если не задано, используется тип связи.
+	Name string `json:"name,omitempty"`
+	// Роли определяются атрибутами связи (инструментами, типами вокала), а
+	// наименование роли используется только при их отсутствии.
+	Attributes bool       `json:"attributes,omitempty"`
+	Target     RoleTarget `json:"target"`
+}
+
+// RoleMapping описывает соответствие типов связей Musicbrainz ролям акторов.
+type RoleMapping map[string]Role
+
+// DefaultRoles содержит соответствие типов связей ролям по умолчанию.
+// Типы связей, отсутствующие в таблице, становятся ролями участников записи.
+var DefaultRoles = RoleMapping{
+	// исполнители
+	"instrument":           {Name: "instrument", Attributes: true},
+	"vocal":                {Name: "vocals", Attributes: true},
+	"performer":            {},
+	"performing orchestra": {Name: "orchestra"},
+	"conductor":            {},
+	"chorus master":        {},
+	"concertmaster":        {},
+	"instrument arranger":  {Name: "arranger"},
+	"vocal arranger":       {},
+	"orchestrator":         {},
+	"arranger":             {},
+	"remixer":              {},
+	"DJ-mix":               {Name: "DJ mix"},
+	"programming":          {},
+	// производство
+	"producer":        {},
+	"engineer":        {},
+	"audio":           {Name: "audio engineer"},
+	"sound":           {Name: "sound engineer"},
+	"recording":       {Name: "recording engineer"},
+	"mix":             {Name: "mixing engineer"},
+	"mastering":       {Name: "mastering engineer"},
+	"editor":          {},
+	"balance":         {Name: "balance engineer"},
+	"field recordist": {},
+	// авторы произведения
+	"composer":   {Target: TargetComposition},
+	"lyricist":   {Target: TargetComposition},
+	"writer":     {Target: TargetComposition},
+	"librettist": {Target: TargetComposition},
+	"translator": {Target: TargetComposition},
+	"revised by": {Name: "reviser", Target: TargetComposition},
+	// оформление и сопроводительные материалы
+	"design":              {Target: TargetTrack},
+	"illustration":        {Target: TargetTrack},
+	"design/illustration": {Target: TargetTrack},
+	"graphic design":      {Target: TargetTrack},
+	"art direction":       {Target: TargetTrack},
+	"artwork":             {Target: TargetTrack},
+	"photography":         {Target: TargetTrack},
+	"creative direction":  {Target: TargetTrack},
+	"liner notes":         {Target: TargetTrack},
+	"booklet editor":      {Target: TargetTrack},
+	"compiler":            {Target: TargetTrack},
+}
+
+// Атрибуты связей, уточняющие роль, и способ их отображения в наименовании роли.
+// Порядок определяет порядок уточнений в наименовании.
+var roleModifiers = []struct {
+	attr   string
+	format string
+}{
+	{"executive", "executive %s"},
+	{"co", "co-%s"},
+	{"associate", "associate %s"},
+	{"assistant", "assistant %s"},
+	{"additional", "additional %s"},
+	{"guest", "guest %s"},
+	{"solo", "solo %s"},
+}
+
+// Атрибуты связей, не влияющие на наименование роли.
+var ignoredRoleAttributes = []string{"task", "live", "cover", "medley", "partial", "instrumental"}
+
+// Override возвращает таблицу соответствия, дополненную (переопределенную)
+// правилами other.
+func (rm RoleMapping) Override(other RoleMapping) RoleMapping {
+	ret := make(RoleMapping, len(rm)+len(other))
+	for k, v := range rm {
+		ret[k] = v
+	}
+	for k, v := range other {
+		ret[k] = v
+	}
+	return ret
+}
+
+// Roles возвращает роли артиста связи и коллекцию трека для их размещения.
+func (rm RoleMapping) Roles(rel *relation) ([]string, RoleTarget) {
+	role, ok := rm[rel.Type]
+	if !ok {
+		role = Role{}
+	}
+	name := role.Name
+	if name == "" {
+		name = rel.Type
+	}
+	var modifiers, bases []string
+	for _, attr := range rel.Attributes {
+		switch {
+		case isRoleModifier(attr):
+			modifiers = append(modifiers, attr)
+		case isIgnoredRoleAttribute(attr):
+		case role.Attributes:
+			bases = append(bases, attr)
+		}
+	}
+	if len(bases) == 0 {
+		bases = []string{name}
+	}
+	var roles []string
+	for _, base := range bases {
+		for i := len(roleModifiers) - 1; i >= 0; i-- {
+			for _, modifier := range modifiers {
+				if modifier == roleModifiers[i].attr {
+					base = fmt.Sprintf(roleModifiers[i].format, base)
+				}
+			}
+		}
+		roles = append(roles, base)
+	}
+	return roles, role.Target
+}
+
+func isRoleModifier(attr string) bool {
+	for _, rm := range roleModifiers {
+		if rm.attr == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func isIgnoredRoleAttribute(attr string) bool {
+	for _, ia := range ignoredRoleAttributes {
+		if strings.EqualFold(ia, attr) {
+			return true
+		}
+	}
+	return false
+}
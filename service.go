@@ -3,12 +3,16 @@ package musicbrainz
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -33,33 +37,164 @@ const (
 
 // Client constants
 const (
-	BaseURL       = "https://musicbrainz.org/ws/2/"
-	ImgURL        = "https://coverartarchive.org"
-	releaseParams = "?inc=annotation+release-groups+artist-credits+recordings+recording-level-rels+artist-rels+genres+labels&fmt=json"
+	BaseURL    = "https://musicbrainz.org/ws/2/"
+	ImgURL     = "https://coverartarchive.org"
+	mirrorPath = "/ws/2/"
+	releaseInc = "annotation+release-groups+artist-credits+recordings+recording-level-rels+release-group-level-rels+artist-rels+url-rels+work-rels+release-rels+genres+tags+ratings+aliases+labels"
+	userInc    = "+user-ratings+user-tags"
 	// releaseGroupParams = "?inc=annotation&fmt=json"
-	// debugURL = "https://musicbrainz.org/ws/2/release/%s?inc=artist-credits+recordings+recording-level-rels+artist-rels+genres+labels&fmt=json"
+	// debugURL = "https://musicbrainz.org/ws/2/release/%s?inc=artist-credits+recordings+recording-level-rels+release-group-level-rels+artist-rels+url-rels+genres+tags+labels&fmt=json"
 	// prodURL        = "https://musicbrainz.org/release/%s"
 	// artistDebugURL = "https://musicbrainz.org/ws/2/artist/%s?inc=releases&fmt=json"
 	// artistProdURL  = "https://musicbrainz.org/artist/%s"
 )
 
+// Profile описывает режим опроса сервера Musicbrainz.
+type Profile struct {
+	PollingInterval time.Duration // интервал между запросами одного потока
+	Concurrency     int           // количество одновременно выполняемых запросов
+}
+
+// Профили опроса публичного сервера (с ограничением частоты запросов) и зеркала.
+var (
+	PublicProfile = Profile{PollingInterval: 2500 * time.Millisecond, Concurrency: 1}
+	MirrorProfile = Profile{PollingInterval: 50 * time.Millisecond, Concurrency: 4}
+)
+
 // Musicbrainz describes data of Musicbrainz client.
 type Musicbrainz struct {
 	*srv.Service
-	headers map[string]string
-	poller  *srv.WebPoller
+	headers  map[string]string
+	baseURL  string
+	imgURL   string
+	profile  Profile
+	retry    RetryPolicy
+	fetcher  Fetcher
+	throttle *throttle
+	roles    RoleMapping
+	genres   GenrePolicy
+	userData bool
+	notes    NotesFormat
+}
+
+// Option описывает параметр конфигурации клиента Musicbrainz.
+type Option func(*Musicbrainz)
+
+// WithBaseURL задает адрес WS/2 API (например, "http://localhost:5000/ws/2/").
+func WithBaseURL(u string) Option {
+	return func(m *Musicbrainz) {
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+		m.baseURL = u
+	}
+}
+
+// WithImgURL задает адрес Cover Art Archive.
+func WithImgURL(u string) Option {
+	return func(m *Musicbrainz) {
+		m.imgURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// WithProfile задает интервал опроса и количество одновременных запросов.
+func WithProfile(p Profile) Option {
+	return func(m *Musicbrainz) {
+		if p.Concurrency < 1 {
+			p.Concurrency = 1
+		}
+		m.profile = p
+	}
+}
+
+// WithRetryPolicy задает правила повтора запросов, отклоненных сервером.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(m *Musicbrainz) {
+		m.retry = p
+	}
+}
+
+// WithMirror настраивает клиент на работу с зеркалом Musicbrainz (musicbrainz-docker),
+// не ограничивающим частоту запросов. Адрес зеркала указывается без пути API,
+// например "http://localhost:5000".
+func WithMirror(host string) Option {
+	return func(m *Musicbrainz) {
+		WithBaseURL(strings.TrimSuffix(host, "/") + mirrorPath)(m)
+		WithProfile(MirrorProfile)(m)
+	}
+}
+
+// WithFetcher задает способ получения http-ресурсов вместо пула WebPoller.
+func WithFetcher(f Fetcher) Option {
+	return func(m *Musicbrainz) {
+		m.fetcher = f
+	}
+}
+
+// WithRoles дополняет (переопределяет) таблицу соответствия типов связей
+// Musicbrainz ролям акторов.
+func WithRoles(roles RoleMapping) Option {
+	return func(m *Musicbrainz) {
+		m.roles = m.roles.Override(roles)
+	}
+}
+
+// WithGenrePolicy задает правила выбора жанров записей.
+func WithGenrePolicy(p GenrePolicy) Option {
+	return func(m *Musicbrainz) {
+		m.genres = p
+	}
+}
+
+// WithUserData включает в ответы оценки и теги пользователя, от имени которого
+// выполняются запросы. Требует ключа доступа OAuth (параметр key функции New).
+func WithUserData() Option {
+	return func(m *Musicbrainz) {
+		m.userData = true
+	}
+}
+
+// WithNotesFormat задает форму представления аннотаций Musicbrainz в примечаниях
+// по умолчанию (NotesText).
+func WithNotesFormat(f NotesFormat) Option {
+	return func(m *Musicbrainz) {
+		m.notes = f
+	}
+}
+
+// WithDump переключает клиент на работу с локальным дампом Musicbrainz вместо
+// обращения к musicbrainz.org.
+func WithDump(d *Dump) Option {
+	return WithFetcher(d)
 }
 
 // New create a new Musicbrainz client.
-func New(app, key, secret string) *Musicbrainz {
+func New(app, key, secret string, opts ...Option) *Musicbrainz {
 	ret := &Musicbrainz{
 		Service: srv.NewService(ServiceName),
 		headers: map[string]string{
 			"User-Agent": app,
-			// "Authorization": "Musicbrainz token=" + key,
 		},
-		poller: srv.NewWebPoller(2500 * time.Millisecond)}
-	ret.poller.Log = ret.Log
+		baseURL: BaseURL,
+		imgURL:  ImgURL,
+		profile: PublicProfile,
+		retry:   DefaultRetryPolicy,
+		roles:   DefaultRoles,
+		genres:  DefaultGenrePolicy,
+		notes:   NotesText}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	if ret.userData && key != "" {
+		ret.headers["Authorization"] = "Bearer " + key
+	} else {
+		ret.userData = false
+	}
+	if ret.fetcher == nil {
+		ret.fetcher = NewPollerFetcher(
+			ret.profile.PollingInterval, ret.profile.Concurrency, ret.Log)
+	}
+	ret.throttle = newThrottle(ret.profile.PollingInterval, ret.fetcher)
 	return ret
 }
 
@@ -78,10 +213,18 @@ func (m *Musicbrainz) AnswerWithError(delivery *amqp.Delivery, err error, contex
 }
 
 // TestPollingInterval выполняет определение частоты опроса сервера на примере
-// тестового запроса. Периодичность расчитывается в наносекундах.
-// TODO: реализовать тестовый запрос.
+// тестового запроса. Интервал подстраивается по заголовкам X-RateLimit-* ответа.
 func (m *Musicbrainz) TestPollingInterval() {
-	// m.Log.Info("Polling interval: ", m.poller.PollingInterval())
+	if _, ok := m.fetcher.(*PollerFetcher); !ok {
+		return
+	}
+	testURL := m.baseURL + "release?query=" + url.PathEscape(queryParam("release", "test")) +
+		"&limit=1&fmt=json"
+	if _, err := m.get(testURL); err != nil {
+		m.LogOnErrorWithContext(err, "Polling interval test")
+		return
+	}
+	m.Log.Info("Polling interval: ", m.throttle.Interval())
 }
 
 // StartWithConnection запускает Web Poller и цикл обработки взодящих запросов.
@@ -89,10 +232,12 @@ func (m *Musicbrainz) TestPollingInterval() {
 func (m *Musicbrainz) StartWithConnection(connstr string) {
 	msgs := m.Service.ConnectToMessageBroker(connstr)
 
-	m.poller.Start()
+	if pf, ok := m.fetcher.(*PollerFetcher); ok {
+		pf.Start()
+	}
 	go m.TestPollingInterval()
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
@@ -115,6 +260,9 @@ func (m *Musicbrainz) StartWithConnection(connstr string) {
 
 func (m *Musicbrainz) cleanup() {
 	m.Service.Cleanup()
+	if closer, ok := m.fetcher.(io.Closer); ok {
+		m.LogOnError(closer.Close())
+	}
 }
 
 // Отображение сведений о выполняемом запросе.
@@ -148,6 +296,10 @@ func (m *Musicbrainz) RunCmd(req *AudioOnlineRequest, delivery *amqp.Delivery) {
 	switch req.Cmd {
 	case "release":
 		data, err = m.release(req)
+	case "browse":
+		data, err = m.browse(req)
+	case "discography":
+		data, err = m.discography(req)
 	default:
 		m.Service.RunCmd(req.Cmd, delivery)
 		return
@@ -168,9 +320,11 @@ func (m *Musicbrainz) release(request *AudioOnlineRequest) (_ []byte, err error)
 	var set *md.SuggestionSet
 
 	if _, ok := request.Release.IDs[md.MusicbrainzAlbumID]; ok {
-		set, err = m.searchReleaseByID(request.Release.IDs[md.MusicbrainzAlbumID])
+		set, err = m.searchReleaseByID(
+			request.Release.IDs[md.MusicbrainzAlbumID], m.mapping(request))
 	} else {
-		set, err = m.searchReleaseByIncompleteData(request.Release)
+		set, err = m.searchReleaseByIncompleteData(
+			request.Release, request.Types, m.mapping(request))
 	}
 	if err != nil {
 		return
@@ -181,9 +335,9 @@ func (m *Musicbrainz) release(request *AudioOnlineRequest) (_ []byte, err error)
 	return json.Marshal(AudioOnlineResponse{SuggestionSet: set})
 }
 
-func (m *Musicbrainz) searchReleaseByID(id string) (*md.SuggestionSet, error) {
+func (m *Musicbrainz) searchReleaseByID(id string, mp *mapping) (*md.SuggestionSet, error) {
 	r := md.NewRelease()
-	if err := m.releaseByID(id, r); err != nil {
+	if err := m.releaseByID(id, r, mp); err != nil {
 		return nil, err
 	}
 	set := md.NewSuggestionSet()
@@ -196,18 +350,22 @@ func (m *Musicbrainz) searchReleaseByID(id string) (*md.SuggestionSet, error) {
 	return set, nil
 }
 
-func (m *Musicbrainz) searchReleaseByIncompleteData(release *md.Release) (
+func (m *Musicbrainz) searchReleaseByIncompleteData(
+	release *md.Release, types *ReleaseTypeFilter, mp *mapping) (
 	*md.SuggestionSet, error) {
 	var suggestions []*md.Suggestion
 	// musicbrainz release search...
 	var preResult releaseSearchResult
-	if err := m.poller.DecodeJSON(searchURL(release), m.headers, &preResult); err != nil {
+	if err := m.decodeJSON(searchURL(m.baseURL, release, types), &preResult); err != nil {
 		return nil, err
 	}
 	var score float64
 	// предварительные предложения
 	for _, r := range preResult.Search() {
-		if score = release.Compare(r); score > MinSearchShortResult {
+		if !types.Accept(r) {
+			continue
+		}
+		if score = types.Boost(r, release.Compare(r)); score > MinSearchShortResult {
 			suggestions = append(
 				suggestions,
 				&md.Suggestion{
@@ -220,12 +378,12 @@ func (m *Musicbrainz) searchReleaseByIncompleteData(release *md.Release) (
 	suggestions = md.BestNResults(suggestions, MaxPreSuggestions)
 	m.Log.WithField("results", len(suggestions)).Debug("Preliminary search")
 	// окончательные предложения
+	if err := m.loadReleases(suggestions, mp); err != nil {
+		return nil, err
+	}
 	for i := len(suggestions) - 1; i >= 0; i-- {
 		r := suggestions[i].Release
-		if err := m.releaseByID(r.IDs[md.MusicbrainzAlbumID], r); err != nil {
-			return nil, err
-		}
-		if score = release.Compare(r); score > MinSearchFullResult {
+		if score = types.Boost(r, release.Compare(r)); types.Accept(r) && score > MinSearchFullResult {
 			suggestions[i].SourceSimilarity = score
 		} else {
 			suggestions = append(suggestions[:i], suggestions[i+1:]...)
@@ -240,21 +398,90 @@ func (m *Musicbrainz) searchReleaseByIncompleteData(release *md.Release) (
 	return set, nil
 }
 
-func (m *Musicbrainz) releaseByID(id string, release *md.Release) error {
+// Загрузка полных данных предварительно найденных релизов с учетом
+// допустимого количества одновременных запросов.
+func (m *Musicbrainz) loadReleases(suggestions []*md.Suggestion, mp *mapping) error {
+	errs := make(chan error, len(suggestions))
+	var wg sync.WaitGroup
+	for _, suggestion := range suggestions {
+		wg.Add(1)
+		go func(r *md.Release) {
+			defer wg.Done()
+			errs <- m.releaseByID(r.IDs[md.MusicbrainzAlbumID], r, mp)
+		}(suggestion.Release)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Musicbrainz) releaseByID(id string, release *md.Release, mp *mapping) error {
 	// release request...
 	var releaseResp releaseInfo
-	if err := m.poller.DecodeJSON(
-		BaseURL+"release/"+id+releaseParams, m.headers, &releaseResp); err != nil {
+	if err := m.decodeJSON(m.releaseURL(id), &releaseResp); err != nil {
 		return err
 	}
-	releaseResp.Release(release)
+	alt := m.scriptVersion(&releaseResp, mp.script)
+	if alt != nil {
+		releaseResp.overlayScript(alt)
+	}
+	releaseResp.Release(release, mp)
+	if alt != nil {
+		release.Unprocessed[ScriptVersionKey] = alt.ID
+	}
 	return nil
 }
 
+// Версия релиза в запрошенной письменности, связанная с ним отношением
+// "transl-tracklisting", или nil, если она не требуется или не найдена.
+func (m *Musicbrainz) scriptVersion(ri *releaseInfo, script string) *releaseInfo {
+	if script == "" || strings.EqualFold(ri.TextRepresentation.Script, script) {
+		return nil
+	}
+	for _, id := range ri.translationsIn(script) {
+		var alt releaseInfo
+		if err := m.decodeJSON(m.releaseURL(id), &alt); err != nil {
+			m.LogOnErrorWithContext(err, "release "+id)
+			continue
+		}
+		if strings.EqualFold(alt.TextRepresentation.Script, script) {
+			return &alt
+		}
+	}
+	return nil
+}
+
+// Адрес запроса полных сведений о релизе.
+func (m *Musicbrainz) releaseURL(id string) string {
+	inc := releaseInc
+	if m.userData {
+		inc += userInc
+	}
+	return m.baseURL + "release/" + id + "?inc=" + inc + "&fmt=json"
+}
+
+// Параметры преобразования данных Musicbrainz в общий формат для запроса.
+func (m *Musicbrainz) mapping(request *AudioOnlineRequest) *mapping {
+	mp := &mapping{roles: m.roles, genres: m.genres, notes: m.notes}
+	if request != nil {
+		mp.locale = request.Locale
+		mp.script = request.Script
+		if request.NotesFormat != "" {
+			mp.notes = request.NotesFormat
+		}
+	}
+	return mp
+}
+
 func (m *Musicbrainz) pictures(entityType, id string) ([]*md.PictureInAudio, error) {
 	var ret []*md.PictureInAudio
 	var ci coverInfo
-	if err := m.poller.DecodeJSON(coverURL(entityType, id), m.headers, &ci); err != nil {
+	if err := m.decodeJSON(coverURL(m.imgURL, entityType, id), &ci); err != nil {
 		return nil, err
 	}
 	if cover := ci.Cover(); cover != nil {
@@ -263,7 +490,59 @@ func (m *Musicbrainz) pictures(entityType, id string) ([]*md.PictureInAudio, err
 	return ret, nil
 }
 
-func searchURL(release *md.Release) string {
+// Загрузка и декодирование JSON данных ресурса из выбранного источника.
+func (m *Musicbrainz) decodeJSON(url string, out interface{}) error {
+	data, err := m.get(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Загрузка ресурса с повтором запросов, отклоненных сервером из-за превышения
+// частоты запросов или временной недоступности.
+func (m *Musicbrainz) get(url string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := m.fetcher.Fetch(url, m.headers)
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			lastErr = err
+		case resp == nil:
+			lastErr = errors.New("no Internet connection")
+		case resp.StatusCode/100 == 2:
+			defer resp.Body.Close()
+			m.throttle.adapt(resp.Header, time.Now())
+			return ioutil.ReadAll(resp.Body)
+		case isRetryable(resp.StatusCode):
+			resp.Body.Close()
+			m.throttle.slowDown()
+			wait = retryAfter(resp.Header, time.Now())
+			lastErr = &RateLimitError{
+				URL:        url,
+				StatusCode: resp.StatusCode,
+				Attempts:   attempt + 1,
+				RetryAfter: wait}
+		default:
+			resp.Body.Close()
+			return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode}
+		}
+
+		// ожидание, запрошенное сервером сверх MaxDelay, остается на усмотрение вызывающего
+		if attempt >= m.retry.MaxRetries || (m.retry.MaxDelay > 0 && wait > m.retry.MaxDelay) {
+			return nil, lastErr
+		}
+		if delay := m.retry.backoff(attempt); delay > wait {
+			wait = delay
+		}
+		m.Log.WithField("delay", wait).Warn(lastErr)
+		time.Sleep(wait)
+	}
+}
+
+func searchURL(baseURL string, release *md.Release, types *ReleaseTypeFilter) string {
 	p := []string{}
 	if performers := release.ActorRoles.Filter(md.IsPerformer); len(performers) > 0 {
 		firstPerformer := performers.First()
@@ -290,18 +569,19 @@ func searchURL(release *md.Release) string {
 			p = append(p, queryParam("catno", labels[0].Catno))
 		}
 	}
+	p = append(p, types.query()...)
 	// if release.Year != 0 {
 	// 	p = append(p, queryParam("date", strconv.Itoa(int(release.Year))))
 	// }
-	buffer := bytes.NewBufferString(BaseURL)
+	buffer := bytes.NewBufferString(baseURL)
 	buffer.WriteString("release?query=")
 	buffer.WriteString(url.PathEscape(strings.Join(p, " AND ")))
 	buffer.WriteString("&fmt=json")
 	return buffer.String()
 }
 
-func coverURL(entity, releaseID string) string {
-	return ImgURL + "/" + entity + "/" + releaseID
+func coverURL(imgURL, entity, releaseID string) string {
+	return imgURL + "/" + entity + "/" + releaseID
 }
 
 func queryParam(k, v string) string {
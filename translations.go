@@ -0,0 +1,122 @@
+// Транслитерированные и переведенные версии релизов (псевдо-релизы Musicbrainz,
+// https://musicbrainz.org/doc/Style/Specific_types_of_releases/Pseudo-Releases).
+
+package musicbrainz
+
+import (
+	"strings"
+
+	md "github.com/ytsiuryn/ds-audiomd"
+)
+
+// Тип связи между релизом и его транслитерированной или переведенной версией.
+const translTracklisting = "transl-tracklisting"
+
+// Ключи дополнительных сведений релиза о письменности и связанных версиях.
+const (
+	ScriptKey        = "script"         // письменность списка треков (ISO 15924: "Latn", "Jpan"...)
+	TranslationsKey  = "translations"   // JSON-список TranslatedRelease
+	ScriptVersionKey = "script_version" // MBID версии, из которой взяты наименования в запрошенной письменности
+)
+
+// TranslatedRelease описывает версию релиза, связанную с ним отношением
+// "transl-tracklisting".
+type TranslatedRelease struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Script   string `json:"script,omitempty"`
+	Language string `json:"language,omitempty"`
+	// Связанный релиз является оригиналом, а не транслитерацией или переводом.
+	Original bool `json:"original,omitempty"`
+}
+
+type relatedRelease struct {
+	ID                 string             `json:"id"`
+	Title              string             `json:"title"`
+	Status             string             `json:"status"`
+	TextRepresentation textRepresentation `json:"text-representation"`
+}
+
+// Translations возвращает связанные версии релиза.
+func Translations(r *md.Release) ([]TranslatedRelease, error) {
+	var ret []TranslatedRelease
+	err := extraJSON(r.Unprocessed, TranslationsKey, &ret)
+	return ret, err
+}
+
+func (rel *relation) addTranslation(stub *md.ReleaseStub) {
+	if rel.Type != translTracklisting || rel.Release == nil {
+		return
+	}
+	var translations []TranslatedRelease
+	extraJSON(stub.Unprocessed, TranslationsKey, &translations)
+	translations = append(translations, TranslatedRelease{
+		ID:       rel.Release.ID,
+		Title:    rel.Release.Title,
+		Script:   rel.Release.TextRepresentation.Script,
+		Language: rel.Release.TextRepresentation.Language,
+		Original: rel.Direction == "backward",
+	})
+	setExtraJSON(stub.Unprocessed, TranslationsKey, translations)
+}
+
+// Кандидаты на роль версии релиза в письменности script: связанные релизы с
+// указанной или неизвестной письменностью.
+func (ri *releaseInfo) translationsIn(script string) []string {
+	var ret []string
+	for _, rel := range ri.Relations {
+		if rel.Type != translTracklisting || rel.Release == nil {
+			continue
+		}
+		s := rel.Release.TextRepresentation.Script
+		if s == "" || strings.EqualFold(s, script) {
+			ret = append(ret, rel.Release.ID)
+		}
+	}
+	return ret
+}
+
+// Замена наименований релиза наименованиями его версии в другой письменности:
+// названия релиза и треков, письменность списка треков и имена в указаниях
+// артистов. Треки сопоставляются по номеру носителя и позиции, указания
+// артистов - по MBID артиста. Прочие сведения остаются от исходного релиза.
+func (ri *releaseInfo) overlayScript(alt *releaseInfo) {
+	if alt.Title != "" {
+		ri.Title = alt.Title
+	}
+	ri.TextRepresentation = alt.TextRepresentation
+	ri.ArtistCredit.overlay(alt.ArtistCredit)
+	titles := map[[2]int]*track{}
+	for i := range alt.Media {
+		for j := range alt.Media[i].Tracks {
+			tr := &alt.Media[i].Tracks[j]
+			titles[[2]int{alt.Media[i].number(i), int(tr.Position)}] = tr
+		}
+	}
+	for i := range ri.Media {
+		for j := range ri.Media[i].Tracks {
+			tr := &ri.Media[i].Tracks[j]
+			altTrack, ok := titles[[2]int{ri.Media[i].number(i), int(tr.Position)}]
+			if !ok {
+				continue
+			}
+			if altTrack.Title != "" {
+				tr.Title = altTrack.Title
+			}
+			tr.ArtistCredits.overlay(altTrack.ArtistCredits)
+		}
+	}
+}
+
+// Замена имен в указании артистов именами из указания в другой письменности.
+func (acs artistCredits) overlay(alt artistCredits) {
+	for i := range acs {
+		for _, ac := range alt {
+			if ac.Artist.ID != "" && ac.Artist.ID == acs[i].Artist.ID && ac.Name != "" {
+				acs[i].Name = ac.Name
+				acs[i].JoinPhrase = ac.JoinPhrase
+				break
+			}
+		}
+	}
+}